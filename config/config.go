@@ -4,9 +4,23 @@
 package config
 
 import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -19,70 +33,1492 @@ type Manager struct {
 	flags      *pflag.FlagSet
 	target     any
 	configFile string
+	nameTag    string
+	transforms map[string]func(string) string
+	parsers    map[string]func(string) error
+	// unitParsers parse values with units into the numbers of flags by flag name.
+	unitParsers map[string]func(string) (float64, error)
+	warn        func(msg string)
+	setFlag     bool
+	sets        []string
+	interpolate bool
+	// descriptions maps flag names to usage strings that take precedence over the description tag.
+	descriptions map[string]string
+	// sources maps flag names to the source of their value when source tracking is enabled.
+	sources map[string]string
+	// normalize rewrites each name tag before it's used in a flag name.
+	normalize func(string) string
+	// decoderOpts configure the yaml decoder of the config file.
+	decoderOpts []func(*yaml.Decoder)
+	// activeEnvironmentKey and environmentsKey select the environment overlay of the config file.
+	activeEnvironmentKey string
+	environmentsKey      string
+	// defaults holds the values set with WithDefaults.
+	defaults any
+	// indexedKeys collapses indexed keys like tags.0 into lists.
+	indexedKeys bool
+	// initial holds a copy of the default value of every field by flag name.
+	initial map[string]reflect.Value
+	// redactKeys holds the key names whose values are masked like secret:"true" fields.
+	redactKeys []string
+	// exclusive holds groups of flags of which at most one may differ from its default.
+	exclusive [][]string
+	// expandEnv and expandEnvStrict expand environment variables in the config file.
+	expandEnv       bool
+	expandEnvStrict bool
+	// emptyAsUnset ignores empty string values in the config file.
+	emptyAsUnset bool
+	// precedence holds the sources in order from lowest to highest precedence.
+	precedence []Source
+	// marshal replaces the marshaler of the format in DumpConfig.
+	marshal func(any) ([]byte, error)
+	// raw holds the contents of the last config file read, after decompression.
+	raw []byte
+	// embedded and embeddedFormat hold the config file set with WithEmbeddedDefaults.
+	embedded       []byte
+	embeddedFormat string
+	// printConfigFlag registers --print-config, whose format is printConfig, and stdout is where it prints.
+	printConfigFlag bool
+	printConfig     string
+	stdout          io.Writer
+	// envBindings maps flag names to the environment variables bound with BindEnv.
+	envBindings map[string]string
 }
 
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceFlag    = "flag"
+	sourceEnv     = "env"
+)
+
+// Source is a source of configuration values, see WithPrecedence.
+type Source string
+
+const (
+	// SourceFile is the config file.
+	SourceFile Source = sourceFile
+	// SourceFlag is the flags set on the command line, including --set.
+	SourceFlag Source = sourceFlag
+	// SourceEnv is the environment variables bound with BindEnv.
+	SourceEnv Source = sourceEnv
+)
+
+// ErrConfigPrinted is returned by ParseConfiguration after it printed the configuration for --print-config,
+// to signal the caller to exit.
+var ErrConfigPrinted = errors.New("configuration printed")
+
+// defaultPrecedence is the order of the sources from lowest to highest precedence unless WithPrecedence is used.
+var defaultPrecedence = []Source{SourceFile, SourceEnv, SourceFlag}
+
 // New returns a new Manager.
 // Out must be a pointer, else this function panics.
-func New(out any, nameTagOverride string) (*Manager, error) {
+func New(out any, nameTagOverride string, opts ...Option) (*Manager, error) {
 	v := reflect.TypeOf(out).Kind()
 	if v != reflect.Pointer {
 		panic("out is not a pointer")
 	}
 
-	m := &Manager{
-		target: out,
-		flags:  pflag.NewFlagSet("config", pflag.ExitOnError),
+	m := &Manager{
+		target:      out,
+		flags:       pflag.NewFlagSet("config", pflag.ExitOnError),
+		nameTag:     nameTagOverride,
+		transforms:  make(map[string]func(string) string),
+		parsers:     make(map[string]func(string) error),
+		unitParsers: make(map[string]func(string) (float64, error)),
+		stdout:      os.Stdout,
+		warn: func(msg string) {
+			slog.Warn(msg)
+		},
+	}
+	if m.nameTag == "" {
+		m.nameTag = "name"
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.defaults != nil {
+		if err := m.applyDefaults(); err != nil {
+			return m, err
+		}
+	}
+	if m.embedded != nil {
+		if err := m.applyEmbeddedDefaults(); err != nil {
+			return m, err
+		}
+	}
+	// Add the config file flag by default.
+	m.flags.StringVarP(
+		&m.configFile,
+		"config",
+		"c",
+		"./config.yml",
+		"location of the configuration file (default: ./config.yml)",
+	)
+	if m.setFlag {
+		m.flags.StringArrayVar(
+			&m.sets,
+			"set",
+			nil,
+			"set a configuration value by flag name, e.g. --set server.port=9090 (repeatable)",
+		)
+	}
+	if m.printConfigFlag {
+		m.flags.StringVar(
+			&m.printConfig,
+			"print-config",
+			"",
+			"print the resolved configuration as yaml or json and exit",
+		)
+		m.flags.Lookup("print-config").NoOptDefVal = "yaml"
+	}
+	// The slice and map flags set nil fields to empty values, so keep track of them to restore them.
+	fields, err := leafFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem())
+	if err != nil {
+		return m, err
+	}
+	nilFields := make(map[string]bool)
+	for _, f := range fields {
+		if k := f.value.Kind(); (k == reflect.Slice || k == reflect.Map) && f.value.IsNil() {
+			nilFields[f.name] = true
+		}
+	}
+	if err := m.genFlagSet(m.nameTag); err != nil {
+		return m, err
+	}
+
+	fields, err = leafFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem())
+	m.initial = make(map[string]reflect.Value, len(fields))
+	for _, f := range fields {
+		if nilFields[f.name] && f.value.Len() == 0 {
+			f.value.Set(reflect.Zero(f.value.Type()))
+		}
+		m.initial[f.name] = cloneValue(f.value)
+	}
+	return m, err
+}
+
+// Load creates a Manager for target and parses args and the config file into it, with the precedence of
+// ParseConfiguration. The config file is ./config.yml unless args set --config.
+// It covers programs that don't need the Manager otherwise, e.g. in main:
+// config.Load(&cfg, os.Args[1:]).
+func Load(target any, args []string, opts ...Option) error {
+	m, err := New(target, "", opts...)
+	if err != nil {
+		return err
+	}
+	return m.ParseArgs(args)
+}
+
+// ParseConfiguration parses the configuration.
+// Order of precedence; config file < environment < --set < flag, unless changed with WithPrecedence.
+// Only the environment variables bound with BindEnv are read.
+// Warnings are passed to the warning handler.
+func (m *Manager) ParseConfiguration(cmd *cobra.Command) error {
+	warnings, err := m.ParseConfigurationWithWarnings(cmd)
+	for _, warning := range warnings {
+		m.warn(warning)
+	}
+	return err
+}
+
+// ParseConfigurationWithWarnings parses the configuration like ParseConfiguration,
+// but returns non-fatal warnings, such as deprecated config keys, instead of passing them to the warning handler.
+// Warnings found before an error are returned along with it.
+func (m *Manager) ParseConfigurationWithWarnings(cmd *cobra.Command) ([]string, error) {
+	return m.parse(cmd.Flags())
+}
+
+// ParseArgs parses args with the generated flags and then parses the configuration like ParseConfiguration.
+// Use it for commands that set DisableFlagParsing, and pass it the args of the command.
+// Warnings are passed to the warning handler.
+func (m *Manager) ParseArgs(args []string) error {
+	fs := pflag.NewFlagSet(m.flags.Name(), pflag.ContinueOnError)
+	// The error is returned, so don't print it with the usage.
+	fs.SetOutput(io.Discard)
+	fs.ParseErrorsAllowlist = m.flags.ParseErrorsAllowlist
+	fs.AddFlagSet(m.flags)
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("could not parse args: %w", err)
+	}
+	return m.Apply(fs)
+}
+
+// Apply parses the configuration like ParseConfiguration, using the flags of fs that were set on the command line.
+// Use it without cobra: fs must be parsed and hold the generated flags, e.g. the FlagSet itself or a flagset
+// they were added to with AddFlagSet.
+// Warnings are passed to the warning handler.
+func (m *Manager) Apply(fs *pflag.FlagSet) error {
+	warnings, err := m.parse(fs)
+	for _, warning := range warnings {
+		m.warn(warning)
+	}
+	return err
+}
+
+// parse merges the config file with the flags in fs that were set on the command line.
+func (m *Manager) parse(fs *pflag.FlagSet) (warnings []string, err error) {
+	secretFlags := m.secretFlags()
+	// Mask the values of secret flags in errors.
+	var secrets []string
+	defer func() {
+		// Errors after the merge may contain the values of secret fields from any source.
+		err = maskSecrets(err, append(secrets, m.flagSecrets(secretFlags)...))
+	}()
+	for _, assignment := range m.sets {
+		if name, value, ok := strings.Cut(assignment, "="); ok && secretFlags[name] {
+			secrets = append(secrets, value)
+		}
+	}
+	envValues := make(map[string]string)
+	for name, envVar := range m.envBindings {
+		if value, ok := os.LookupEnv(envVar); ok {
+			envValues[name] = value
+			if secretFlags[name] {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+
+	// Save explicitly set flag values before loading the yaml.
+	// Slices are saved element-wise, since their string form can't be set back.
+	setFlags := make(map[string]string)
+	setSlices := make(map[string][]string)
+	fs.Visit(func(f *pflag.Flag) {
+		if isManagerFlag(f.Name) {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			setSlices[f.Name] = sv.GetSlice()
+			if secretFlags[f.Name] {
+				secrets = append(secrets, setSlices[f.Name]...)
+			}
+			return
+		}
+		setFlags[f.Name] = f.Value.String()
+		if secretFlags[f.Name] {
+			secrets = append(secrets, setFlags[f.Name])
+		}
+	})
+
+	order := m.precedence
+	if order == nil {
+		order = defaultPrecedence
+	}
+	if !slices.Contains(order, SourceFlag) {
+		// Ignore the flags set on the command line.
+		for name := range setFlags {
+			if err := m.ResetFlag(name); err != nil {
+				return nil, err
+			}
+		}
+		for name := range setSlices {
+			if err := m.ResetFlag(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Apply the sources from lowest to highest precedence.
+	var fileDoc *yaml.Node
+	for _, source := range order {
+		switch source {
+		case SourceFile:
+			// Get values from the config file.
+			doc, fileWarnings, err := m.readFile(m.configFile)
+			warnings = append(warnings, fileWarnings...)
+			fileDoc = doc
+			if m.embedded != nil && !fs.Changed("config") && errors.Is(err, os.ErrNotExist) {
+				// The embedded defaults make the default config file optional.
+				continue
+			}
+			if err != nil {
+				return warnings, err
+			}
+		case SourceFlag:
+			if err := m.applyFlags(fs, setFlags, setSlices); err != nil {
+				return warnings, err
+			}
+		case SourceEnv:
+			if err := m.applyEnv(envValues); err != nil {
+				return warnings, err
+			}
+		}
+	}
+
+	if m.sources != nil {
+		var flagNames []string
+		for _, assignment := range m.sets {
+			name, _, _ := strings.Cut(assignment, "=")
+			flagNames = append(flagNames, name)
+		}
+		for name := range setFlags {
+			flagNames = append(flagNames, name)
+		}
+		for name := range setSlices {
+			flagNames = append(flagNames, name)
+		}
+		if err := m.trackSources(order, fileDoc, envValues, flagNames); err != nil {
+			return warnings, err
+		}
+	}
+
+	if err := m.resolve(); err != nil {
+		return warnings, err
+	}
+
+	if m.printConfig != "" {
+		if err := m.DumpConfig(m.stdout, m.printConfig); err != nil {
+			return warnings, err
+		}
+		return warnings, ErrConfigPrinted
+	}
+
+	// Required fields set to their zero value on the command line or in the environment are set.
+	passed := make(map[string]bool)
+	if slices.Contains(order, SourceEnv) {
+		for name := range envValues {
+			passed[name] = true
+		}
+	}
+	if slices.Contains(order, SourceFlag) {
+		for _, assignment := range m.sets {
+			name, _, _ := strings.Cut(assignment, "=")
+			passed[name] = true
+		}
+		for name := range setFlags {
+			passed[name] = true
+		}
+		for name := range setSlices {
+			passed[name] = true
+		}
+	}
+	return warnings, m.checkRequired(passed, fileDoc)
+}
+
+// applyFlags applies the --set assignments and then the saved values of the flags set on the command line.
+func (m *Manager) applyFlags(fs *pflag.FlagSet, setFlags map[string]string, setSlices map[string][]string) error {
+	// Apply --set assignments over the config file.
+	if err := m.applySets(); err != nil {
+		return err
+	}
+
+	// Override explicitly set flags from the args.
+	for name, value := range setFlags {
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("could not set flag %s: %w", name, err)
+		}
+		if m.sources != nil {
+			m.sources[name] = sourceFlag
+		}
+	}
+	for name, values := range setSlices {
+		sv := fs.Lookup(name).Value.(pflag.SliceValue)
+		if err := sv.Replace(values); err != nil {
+			return fmt.Errorf("could not set flag %s: %w", name, err)
+		}
+		if m.sources != nil {
+			m.sources[name] = sourceFlag
+		}
+	}
+	return nil
+}
+
+// applyEnv sets the flags bound with BindEnv to the values of their environment variables, by flag name.
+// Slices are comma separated and replace the value.
+func (m *Manager) applyEnv(values map[string]string) error {
+	for name, value := range values {
+		f := m.flags.Lookup(name)
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if err := sv.Replace(strings.Split(value, ",")); err != nil {
+				return fmt.Errorf("could not set flag %s from %s: %w", name, m.envBindings[name], err)
+			}
+		} else if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("could not set flag %s from %s: %w", name, m.envBindings[name], err)
+		}
+		if m.sources != nil {
+			m.sources[name] = sourceEnv
+		}
+	}
+	return nil
+}
+
+// BindEnv binds a flag to an environment variable, which ParseConfiguration applies when it's set,
+// with the precedence of SourceEnv: over the config file and under the flags by default.
+func (m *Manager) BindEnv(flagName, envVar string) error {
+	if f := m.flags.Lookup(flagName); f == nil || isManagerFlag(flagName) {
+		return fmt.Errorf("could not bind %s: unknown flag %s", envVar, flagName)
+	}
+	if m.envBindings == nil {
+		m.envBindings = make(map[string]string)
+	}
+	m.envBindings[flagName] = envVar
+	return nil
+}
+
+// LoadFile reads the config file at path into the target without any flags.
+// Fields missing from the file keep their current values, and interpolation, transforms and parsers are applied
+// as they would be by ParseConfiguration.
+func (m *Manager) LoadFile(path string) error {
+	_, warnings, err := m.readFile(path)
+	for _, warning := range warnings {
+		m.warn(warning)
+	}
+	if err != nil {
+		return err
+	}
+	return maskSecrets(m.resolve(), m.flagSecrets(m.secretFlags()))
+}
+
+// Unmarshal decodes the last config file read by ParseConfiguration, ParseArgs or LoadFile into out,
+// which may be of any type yaml can decode into, e.g. a domain model with yaml tags and a subset of the fields.
+// Only the file is decoded: flags, environments and the other options of the Manager don't apply.
+func (m *Manager) Unmarshal(out any) error {
+	if m.raw == nil {
+		return errors.New("could not unmarshal config file: no config file loaded")
+	}
+	if err := yaml.Unmarshal(m.raw, out); err != nil {
+		return fmt.Errorf("could not unmarshal config file: %w", err)
+	}
+	return nil
+}
+
+// readFile reads and decodes the config file at path into the target, and returns warnings about its contents.
+// Gzip compressed files are decompressed first. Files with a .json extension must be valid JSON,
+// and any other file is read as YAML.
+// The values of secret fields are masked in errors.
+func (m *Manager) readFile(path string) (doc *yaml.Node, warnings []string, err error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read config file: %w", err)
+	}
+	if bytes.HasPrefix(raw, gzipMagic) {
+		if raw, err = gunzip(raw); err != nil {
+			return nil, nil, fmt.Errorf("could not read config file: %w", err)
+		}
+	}
+	doc, warnings, err = m.decodeFile(raw, isJSON(path))
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.sources != nil {
+		return doc, warnings, m.trackFileSources(doc)
+	}
+	return doc, warnings, nil
+}
+
+// decodeFile decodes the contents of a config file into the target and returns its document.
+func (m *Manager) decodeFile(raw []byte, isJSON bool) (_ *yaml.Node, warnings []string, err error) {
+	if isJSON {
+		// Yaml reads JSON as well, but would also accept YAML, so check that the file is valid JSON first.
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, nil, fmt.Errorf("could not parse config file: %w", err)
+		}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, fmt.Errorf("could not parse config file: %w", err)
+	}
+	m.raw = raw
+	if m.environmentsKey != "" {
+		if err := m.applyEnvironment(&doc); err != nil {
+			return nil, nil, err
+		}
+	}
+	if isJSON {
+		renameJSONKeys(&doc, reflect.TypeOf(m.target))
+	}
+	if err := m.nestFlattened(&doc); err != nil {
+		return nil, nil, err
+	}
+	// Look up the secrets once the keys are where the fields expect them.
+	secrets := m.fileSecrets(&doc)
+	defer func() {
+		err = maskSecrets(err, secrets)
+	}()
+	if m.indexedKeys {
+		if err := m.collapseIndexedKeys(&doc); err != nil {
+			return nil, nil, err
+		}
+	}
+	if m.expandEnv {
+		if err := expandEnv(&doc, nil, m.expandEnvStrict); err != nil {
+			return nil, nil, err
+		}
+		// Mask the expanded values as well.
+		secrets = append(secrets, m.fileSecrets(&doc)...)
+	}
+	if m.emptyAsUnset {
+		if err := m.removeEmptyStrings(&doc); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := m.rewriteValues(&doc); err != nil {
+		return nil, nil, err
+	}
+	// An empty file has no document to decode.
+	if doc.Kind == yaml.DocumentNode {
+		if err := m.decode(&doc); err != nil {
+			return nil, nil, err
+		}
+	}
+	warnings, err = m.deprecationWarnings(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &doc, warnings, nil
+}
+
+// isJSON returns whether a config file is JSON by its extension, ignoring a .gz suffix.
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(strings.TrimSuffix(path, ".gz")), ".json")
+}
+
+// gzipMagic starts every gzip compressed file.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gunzip decompresses gzip compressed data.
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// decode decodes a config file into the target with the decoder options.
+// Fields that implement pflag.Value are set with their Set method instead.
+func (m *Manager) decode(doc *yaml.Node) error {
+	// Decode a copy of the document, so that the keys of pflag.Value fields can be removed from it.
+	raw, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("could not parse config file: %w", err)
+	}
+	var stripped yaml.Node
+	if err := yaml.Unmarshal(raw, &stripped); err != nil {
+		return fmt.Errorf("could not parse config file: %w", err)
+	}
+	var fields []field
+	var values []pflag.Value
+	var scalars []string
+	_ = walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		value, ok := f.value.Addr().Interface().(pflag.Value)
+		if !ok && f.value.Kind() == reflect.Interface {
+			// Interface fields are set through their flag, which keeps the type of their value.
+			if fl := m.flags.Lookup(f.name); fl != nil {
+				value, ok = fl.Value.(*interfaceValue)
+			}
+		}
+		if !ok {
+			return nil
+		}
+		node := lookupNode(&stripped, f.path)
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return nil
+		}
+		removeKey(parentNode(&stripped, f.path), f.path[len(f.path)-1])
+		fields = append(fields, f)
+		values = append(values, value)
+		scalars = append(scalars, node.Value)
+		return nil
+	})
+	if raw, err = yaml.Marshal(&stripped); err != nil {
+		return fmt.Errorf("could not parse config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	for _, opt := range m.decoderOpts {
+		opt(dec)
+	}
+	if err := dec.Decode(m.target); err != nil {
+		return fmt.Errorf("could not parse config file: %w", err)
+	}
+	for i, value := range values {
+		if err := value.Set(scalars[i]); err != nil {
+			return fmt.Errorf("could not parse config file: key %s: %w", strings.Join(fields[i].path, "."), err)
+		}
+	}
+	return nil
+}
+
+// resolve interpolates, transforms and parses the merged values.
+func (m *Manager) resolve() error {
+	for name := range m.unitParsers {
+		if m.flags.Lookup(name) == nil {
+			return fmt.Errorf("could not parse units of flag %s: flag not found", name)
+		}
+	}
+
+	if m.interpolate {
+		if err := interpolate(m.flags); err != nil {
+			return err
+		}
+	}
+
+	if err := m.applyTransforms(); err != nil {
+		return err
+	}
+
+	if err := m.applyParsers(); err != nil {
+		return err
+	}
+
+	if err := m.checkExclusive(); err != nil {
+		return err
+	}
+
+	if err := m.checkRanges(); err != nil {
+		return err
+	}
+
+	if err := m.checkChoices(); err != nil {
+		return err
+	}
+
+	return m.checkPaths()
+}
+
+// FlagSet returns the manager's flagset.
+func (m *Manager) FlagSet() *pflag.FlagSet {
+	return m.flags
+}
+
+// isManagerFlag returns whether a flag is one of the flags of the Manager itself, such as --config,
+// rather than generated from the struct.
+func isManagerFlag(name string) bool {
+	return name == "config" || name == "set" || name == "print-config"
+}
+
+// UserFlagSet returns a flagset with only the flags generated from the struct, without --config and --set.
+// The flags are shared with FlagSet, so setting them sets the struct fields.
+func (m *Manager) UserFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("config", pflag.ExitOnError)
+	m.flags.VisitAll(func(f *pflag.Flag) {
+		if !isManagerFlag(f.Name) {
+			fs.AddFlag(f)
+		}
+	})
+	return fs
+}
+
+// ResetFlag restores a flag and its field to the default value they had when the Manager was created.
+// The --no-<flag> negations and the flags of slice elements reset the field they set.
+func (m *Manager) ResetFlag(name string) error {
+	f := m.flags.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("could not reset flag %s: flag not found", name)
+	}
+	owner := m.flagOwner(name, f)
+	initial, ok := m.initial[owner]
+	if !ok {
+		return fmt.Errorf("could not reset flag %s: flag not found", name)
+	}
+	fields, err := leafFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem())
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.name != owner {
+			continue
+		}
+		field.value.Set(cloneValue(initial))
+		// The values of slice and map flags append to what they were set to before, so they're created again.
+		if of := m.flags.Lookup(owner); of != nil && (field.value.Kind() == reflect.Slice || field.value.Kind() == reflect.Map) {
+			value, err := m.newFlagValue(owner)
+			if err != nil {
+				return err
+			}
+			of.Value = value
+		}
+	}
+	f.Changed = false
+	if of := m.flags.Lookup(owner); of != nil {
+		of.Changed = false
+	}
+	if m.sources != nil {
+		m.sources[owner] = sourceDefault
+	}
+	return nil
+}
+
+// newFlagValue returns a new value for the flag name, bound to the same field as its current value.
+func (m *Manager) newFlagValue(name string) (pflag.Value, error) {
+	target := reflect.ValueOf(m.target).Elem()
+	// processStruct sets the default tags of zero fields, so restore the fields as they are.
+	saved := reflect.New(target.Type()).Elem()
+	saved.Set(target)
+	defer target.Set(saved)
+	fs := pflag.NewFlagSet("reset", pflag.ContinueOnError)
+	if err := processStruct(m.nameTag, m.normalize, fs, target, ""); err != nil {
+		return nil, err
+	}
+	f := fs.Lookup(name)
+	if f == nil {
+		return nil, fmt.Errorf("could not reset flag %s: flag not found", name)
+	}
+	if parse, ok := m.unitParsers[name]; ok {
+		return newUnitValue(f.Value, parse), nil
+	}
+	return f.Value, nil
+}
+
+// flagOwner returns the name of the field that the flag f sets: the bool field of a --no-<flag> negation, the
+// slice field of an element's flag, or name itself.
+func (m *Manager) flagOwner(name string, f *pflag.Flag) string {
+	switch f.Value.(type) {
+	case *negatedBoolValue:
+		return strings.TrimPrefix(name, "no-")
+	case *elementValue, *elementSliceValue:
+		for i, r := range name {
+			if r != '.' {
+				continue
+			}
+			if _, ok := m.initial[name[:i]]; ok {
+				return name[:i]
+			}
+		}
+	}
+	return name
+}
+
+// FlagInfo describes a flag generated from the struct.
+type FlagInfo struct {
+	Name        string
+	Shorthand   string
+	Type        string
+	Default     string
+	Description string
+	Hidden      bool
+	// Deprecated is the deprecation message, or empty if the flag isn't deprecated.
+	Deprecated string
+	// Required is true if the field is tagged required:"true" or the flag was marked required on a cobra command,
+	// e.g. with MarkFlagRequired.
+	Required bool
+}
+
+// Flags returns the metadata of the flags generated from the struct, sorted by name.
+func (m *Manager) Flags() []FlagInfo {
+	required := make(map[string]bool)
+	// walkFields only returns the errors of its callback, and this one has none.
+	_ = walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		required[f.name] = f.structField.Tag.Get("required") == "true"
+		return nil
+	})
+	var flags []FlagInfo
+	m.UserFlagSet().VisitAll(func(f *pflag.Flag) {
+		flags = append(flags, FlagInfo{
+			Name:        f.Name,
+			Shorthand:   f.Shorthand,
+			Type:        f.Value.Type(),
+			Default:     f.DefValue,
+			Description: f.Usage,
+			Hidden:      f.Hidden,
+			Deprecated:  f.Deprecated,
+			Required:    required[f.Name] || slices.Equal(f.Annotations[cobra.BashCompOneRequiredFlag], []string{"true"}),
+		})
+	})
+	return flags
+}
+
+// HelpMarkdown returns a Markdown table of the flags generated from the struct, sorted by name,
+// with their type, default and description, for example to generate documentation. Hidden flags are left out.
+func (m *Manager) HelpMarkdown() string {
+	var b strings.Builder
+	b.WriteString("| Flag | Type | Default | Description |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	escape := strings.NewReplacer("|", "\\|", "\n", " ").Replace
+	for _, f := range m.Flags() {
+		if f.Hidden {
+			continue
+		}
+		name := "`--" + f.Name + "`"
+		if f.Shorthand != "" {
+			name += ", `-" + f.Shorthand + "`"
+		}
+		defaultValue := ""
+		if f.Default != "" {
+			defaultValue = "`" + escape(f.Default) + "`"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", name, f.Type, defaultValue, escape(f.Description))
+	}
+	return b.String()
+}
+
+// ApplyRequired marks the flags of fields tagged required:"true" as required on cmd, which must already have them.
+// Cobra then fails with its standard error when they aren't passed on the command line.
+// Note that this only considers flags, so values from the config file don't satisfy it;
+// ParseConfiguration checks required fields regardless of the source of their values.
+func (m *Manager) ApplyRequired(cmd *cobra.Command) error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.structField.Tag.Get("required") != "true" {
+			return nil
+		}
+		// Required sections have no flag of their own; ParseConfiguration checks them.
+		if f.value.Kind() == reflect.Struct && f.value.Type() != timeType && !isValue(f.value) {
+			return nil
+		}
+		if err := cmd.MarkFlagRequired(f.name); err != nil {
+			return fmt.Errorf("could not mark flag %s required: %w", f.name, err)
+		}
+		return nil
+	})
+}
+
+// BindPersistent adds the manager's flagset to the persistent flags of cmd, so its subcommands inherit them.
+// ParseConfiguration can then be called with cmd or any of its subcommands.
+func (m *Manager) BindPersistent(cmd *cobra.Command) {
+	cmd.PersistentFlags().AddFlagSet(m.flags)
+}
+
+// RegisterTransform registers a function that normalizes the string form of a flag's value.
+// The transform is applied once per ParseConfiguration, after the config file and flags are merged,
+// so it sees the value regardless of the source it came from.
+// Only scalar and slice flags can be transformed.
+func (m *Manager) RegisterTransform(flagName string, fn func(string) string) {
+	m.transforms[flagName] = fn
+}
+
+// RegisterParser registers a function that parses and validates the string form of a flag's value.
+// The parser is called once per ParseConfiguration, after transforms, with the final value regardless of its source,
+// and once per element for slice flags. An error from the parser is returned by ParseConfiguration.
+func (m *Manager) RegisterParser(flagName string, parse func(string) error) {
+	m.parsers[flagName] = parse
+}
+
+// RegisterUnitParser registers a function that parses values with units, such as 10/s or 25C, into the number
+// of a numeric flag, from the config file, --set and the flag itself.
+// Register it before ParseConfiguration; values that aren't whole numbers are an error for integer flags.
+// ParseConfiguration returns an error if the flag doesn't exist.
+func (m *Manager) RegisterUnitParser(flagName string, parse func(string) (float64, error)) {
+	m.unitParsers[flagName] = parse
+	if f := m.flags.Lookup(flagName); f != nil {
+		f.Value = newUnitValue(f.Value, parse)
+	}
+}
+
+// MutuallyExclusive registers a group of flags of which at most one may be set to a value other than its default,
+// from any source. ParseConfiguration returns an error if more than one is.
+func (m *Manager) MutuallyExclusive(names ...string) {
+	m.exclusive = append(m.exclusive, names)
+}
+
+// checkExclusive checks that at most one flag of each mutually exclusive group differs from its default.
+func (m *Manager) checkExclusive() error {
+	if len(m.exclusive) == 0 {
+		return nil
+	}
+	fields, err := leafFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem())
+	if err != nil {
+		return err
+	}
+	values := make(map[string]reflect.Value, len(fields))
+	for _, f := range fields {
+		values[f.name] = f.value
+	}
+	for _, group := range m.exclusive {
+		var set []string
+		for _, name := range group {
+			value, ok := values[name]
+			if !ok {
+				return fmt.Errorf("could not check mutually exclusive flag %s: flag not found", name)
+			}
+			if !reflect.DeepEqual(value.Interface(), m.initial[name].Interface()) {
+				set = append(set, name)
+			}
+		}
+		if len(set) > 1 {
+			return fmt.Errorf("flags %s are mutually exclusive", strings.Join(set, ", "))
+		}
+	}
+	return nil
+}
+
+// checkRanges checks that the numeric fields tagged min or max are within their bounds.
+func (m *Manager) checkRanges() error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		value, ok := indirectField(f.value)
+		if !ok {
+			return nil
+		}
+		for _, bound := range []string{"min", "max"} {
+			limit, ok := f.structField.Tag.Lookup(bound)
+			if !ok {
+				continue
+			}
+			cmp, err := compareNumber(value, limit)
+			if err != nil {
+				return fmt.Errorf("invalid %s tag of field %s: %w", bound, f.structField.Name, err)
+			}
+			if bound == "min" && cmp < 0 {
+				return fmt.Errorf("flag %s of field %s must be at least %s, got %v", f.name, f.structField.Name, limit, value.Interface())
+			}
+			if bound == "max" && cmp > 0 {
+				return fmt.Errorf("flag %s of field %s must be at most %s, got %v", f.name, f.structField.Name, limit, value.Interface())
+			}
+		}
+		return nil
+	})
+}
+
+// checkChoices checks that the string fields tagged choices or enum have one of the comma separated values of the tag,
+// and that every element of string slices does. Empty strings are left to the required tag.
+// Enum tags of other types are only used by JSONSchema.
+func (m *Manager) checkChoices() error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		key := "choices"
+		tag, ok := f.structField.Tag.Lookup(key)
+		if !ok {
+			key = "enum"
+			if tag, ok = f.structField.Tag.Lookup(key); !ok {
+				return nil
+			}
+		}
+		value, ok := indirectField(f.value)
+		if !ok {
+			return nil
+		}
+		choices := splitChoices(tag)
+		switch {
+		case value.Kind() == reflect.String:
+			if value.String() != "" && !slices.Contains(choices, value.String()) {
+				return fmt.Errorf("flag %s of field %s must be one of %s, got %q", f.name, f.structField.Name, strings.Join(choices, ", "), value.String())
+			}
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < value.Len(); i++ {
+				if element := value.Index(i).String(); !slices.Contains(choices, element) {
+					return fmt.Errorf("elements of flag %s of field %s must be one of %s, got %q at index %d", f.name, f.structField.Name, strings.Join(choices, ", "), element, i)
+				}
+			}
+		case key == "choices":
+			return fmt.Errorf("invalid choices tag of field %s: %s fields have no choices", f.structField.Name, value.Type())
+		}
+		return nil
+	})
+}
+
+// indirectField returns the value a pointer field points to, and false if it's nil.
+// Fields that aren't pointers are returned as is.
+func indirectField(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() != reflect.Pointer {
+		return v, true
 	}
-	// Add the config file flag by default.
-	m.flags.StringVarP(
-		&m.configFile,
-		"config",
-		"c",
-		"./config.yml",
-		"location of the configuration file (default: ./config.yml)",
-	)
-	err := m.genFlagSet(nameTagOverride)
-	return m, err
+	if v.IsNil() {
+		return v, false
+	}
+	return v.Elem(), true
 }
 
-// ParseConfiguration parses the configuration.
-// Order of precedence; config file < flag < environment.
-// TODO: Support environment.
-func (m Manager) ParseConfiguration(cmd *cobra.Command) (err error) {
-	// Save explicitly set flag values before loading the yaml.
-	setFlags := make(map[string]string)
-	cmd.Flags().Visit(func(f *pflag.Flag) {
-		if f.Name != "config" {
-			setFlags[f.Name] = f.Value.String()
+// checkPaths checks that the string fields tagged validate:"file" or validate:"dir" are the path of a readable file
+// or directory. Empty strings are left to the required tag.
+func (m *Manager) checkPaths() error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		rule, ok := f.structField.Tag.Lookup("validate")
+		if !ok {
+			return nil
+		}
+		value, ok := indirectField(f.value)
+		if !ok {
+			return nil
+		}
+		if rule != "file" && rule != "dir" {
+			return fmt.Errorf("invalid validate tag of field %s: unknown rule %s", f.structField.Name, rule)
+		}
+		if value.Kind() != reflect.String {
+			return fmt.Errorf("invalid validate tag of field %s: %s fields have no path", f.structField.Name, value.Type())
+		}
+		path := value.String()
+		if path == "" {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("flag %s of field %s must be a readable %s: %w", f.name, f.structField.Name, rule, err)
 		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("flag %s of field %s must be a readable %s: %w", f.name, f.structField.Name, rule, err)
+		}
+		if info.IsDir() != (rule == "dir") {
+			return fmt.Errorf("flag %s of field %s must be a %s, got %s", f.name, f.structField.Name, rule, path)
+		}
+		return nil
 	})
+}
+
+// splitChoices returns the comma separated values of a choices tag.
+func splitChoices(tag string) []string {
+	choices := strings.Split(tag, ",")
+	for i, choice := range choices {
+		choices[i] = strings.TrimSpace(choice)
+	}
+	return choices
+}
+
+// compareNumber compares the value of a numeric field with the number s, returning -1, 0 or +1.
+func compareNumber(v reflect.Value, s string) (int, error) {
+	switch k := v.Kind(); {
+	case v.Type() == durationType:
+		return 0, errors.New("durations have no range")
+	case reflect.Int <= k && k <= reflect.Int64:
+		limit, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not an integer", s)
+		}
+		return cmp.Compare(v.Int(), limit), nil
+	case reflect.Uint <= k && k <= reflect.Uint64:
+		limit, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not an unsigned integer", s)
+		}
+		return cmp.Compare(v.Uint(), limit), nil
+	case k == reflect.Float32 || k == reflect.Float64:
+		limit, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a number", s)
+		}
+		return cmp.Compare(v.Float(), limit), nil
+	}
+	return 0, fmt.Errorf("%s fields have no range", v.Type())
+}
 
-	// Get values from the config file.
-	raw, err := os.ReadFile(m.configFile)
+// checkRequired checks that the fields tagged required:"true" aren't zero, or empty for slices and maps,
+// unless their flag is in passed. Required nested structs are sections that must be in the config file doc,
+// unless a flag of one of their fields is in passed. The error lists all missing fields by flag name.
+func (m *Manager) checkRequired(passed map[string]bool, doc *yaml.Node) error {
+	var missing, sections []string
+	err := walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.structField.Tag.Get("required") != "true" || passed[f.name] {
+			return nil
+		}
+		switch f.value.Kind() {
+		case reflect.Struct:
+			if f.value.Type() == timeType || isValue(f.value) {
+				if f.value.IsZero() {
+					missing = append(missing, f.name)
+				}
+				return nil
+			}
+			if doc != nil && lookupNode(doc, f.path) != nil {
+				return nil
+			}
+			for name := range passed {
+				if strings.HasPrefix(name, f.name+".") {
+					return nil
+				}
+			}
+			sections = append(sections, f.name)
+		case reflect.Slice, reflect.Map:
+			if f.value.Len() == 0 {
+				missing = append(missing, f.name)
+			}
+		default:
+			if f.value.IsZero() {
+				missing = append(missing, f.name)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("could not read config file: %w", err)
+		return err
 	}
-	if err := yaml.Unmarshal(raw, m.target); err != nil {
-		return fmt.Errorf("could not parse config file: %w", err)
+	if len(sections) > 0 {
+		return fmt.Errorf("required sections missing from the config file: %s", strings.Join(sections, ", "))
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required fields not set: %s", strings.Join(missing, ", "))
 	}
+	return nil
+}
 
-	// Override explicitly set flags from the args.
-	for name, value := range setFlags {
-		if err := cmd.Flags().Set(name, value); err != nil {
-			return fmt.Errorf("could not set flag %s: %w", name, err)
+// applySets applies the name=value assignments passed with --set.
+func (m *Manager) applySets() error {
+	for _, assignment := range m.sets {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return fmt.Errorf("could not apply --set %s: expected name=value", assignment)
+		}
+		f := m.flags.Lookup(name)
+		if f == nil || isManagerFlag(name) {
+			return fmt.Errorf("could not apply --set %s: unknown flag %s", assignment, name)
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("could not apply --set %s: %w", assignment, err)
+		}
+		if m.sources != nil {
+			m.sources[name] = sourceFlag
 		}
 	}
 	return nil
 }
 
-// FlagSet returns the manager's flagset.
-func (m Manager) FlagSet() *pflag.FlagSet {
-	return m.flags
+// SourceOf returns where the value of a flag came from in the last ParseConfiguration;
+// one of "default", "file", "env" or "flag".
+// It returns an empty string for unknown flags or if the Manager was created without WithSourceTracking.
+func (m *Manager) SourceOf(flagName string) string {
+	return m.sources[flagName]
+}
+
+// Resolution describes how the value of a flag was resolved by ParseConfiguration.
+type Resolution struct {
+	// Flag is the flag name.
+	Flag string
+	// Source is where the value came from; one of "default", "file", "env" or "flag".
+	Source string
+	// Value is the final value of the flag, in its flag string form.
+	Value string
+	// Overridden is true if the value came from the config file or a flag rather than the default.
+	Overridden bool
+}
+
+// ResolutionReport returns how the value of every flag was resolved in the last ParseConfiguration, sorted by flag name.
+// Fields that have no flag, such as maps of structs, are not included, and the values of secret fields are masked.
+// It returns nil if the Manager was created without WithSourceTracking.
+func (m *Manager) ResolutionReport() []Resolution {
+	if m.sources == nil {
+		return nil
+	}
+	secretFlags := m.secretFlags()
+	var report []Resolution
+	for name, source := range m.sources {
+		f := m.flags.Lookup(name)
+		if f == nil {
+			continue
+		}
+		value := f.Value.String()
+		if secretFlags[name] {
+			value = maskedValue
+		}
+		report = append(report, Resolution{
+			Flag:       name,
+			Source:     source,
+			Value:      value,
+			Overridden: source != sourceDefault,
+		})
+	}
+	slices.SortFunc(report, func(a, b Resolution) int {
+		return strings.Compare(a.Flag, b.Flag)
+	})
+	return report
+}
+
+// trackFileSources resets the tracked sources to the defaults and records the fields set by the config file.
+func (m *Manager) trackFileSources(doc *yaml.Node) error {
+	clear(m.sources)
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.value.Kind() == reflect.Struct && f.value.Type() != timeType {
+			return nil
+		}
+		m.sources[f.name] = sourceDefault
+		if lookupNode(doc, f.path) != nil {
+			m.sources[f.name] = sourceFile
+		}
+		return nil
+	})
+}
+
+// trackSources sets the source of every field to the last source in order that set it: the keys of the config file
+// doc, the flags bound to the environment variables in env, or the flags and --set assignments in flags.
+func (m *Manager) trackSources(order []Source, doc *yaml.Node, env map[string]string, flags []string) error {
+	if doc == nil {
+		doc = &yaml.Node{}
+	}
+	if err := m.trackFileSources(doc); err != nil {
+		return err
+	}
+	var fileNames []string
+	for name, source := range m.sources {
+		if source == sourceFile {
+			fileNames = append(fileNames, name)
+			m.sources[name] = sourceDefault
+		}
+	}
+	for _, source := range order {
+		switch source {
+		case SourceFile:
+			for _, name := range fileNames {
+				m.sources[name] = sourceFile
+			}
+		case SourceEnv:
+			for name := range env {
+				m.sources[name] = sourceEnv
+			}
+		case SourceFlag:
+			for _, name := range flags {
+				m.sources[name] = sourceFlag
+			}
+		}
+	}
+	return nil
+}
+
+// deprecationWarnings returns a warning for every key in the config file whose field has a deprecated tag.
+func (m *Manager) deprecationWarnings(doc *yaml.Node) ([]string, error) {
+	var warnings []string
+	err := walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		replacement := f.structField.Tag.Get("deprecated")
+		if replacement == "" || lookupNode(doc, f.path) == nil {
+			return nil
+		}
+		warnings = append(warnings, fmt.Sprintf("config key %q is deprecated, use %q instead", strings.Join(f.path, "."), replacement))
+		return nil
+	})
+	return warnings, err
+}
+
+// collapseIndexedKeys replaces the indexed keys of slice fields in a config file, like tags.0 and tags.1,
+// with a list ordered by index.
+func (m *Manager) collapseIndexedKeys(doc *yaml.Node) error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.value.Kind() != reflect.Slice {
+			return nil
+		}
+		parent := parentNode(doc, f.path)
+		if parent == nil {
+			return nil
+		}
+
+		key := f.path[len(f.path)-1]
+		type item struct {
+			index int
+			value *yaml.Node
+		}
+		var items []item
+		for i := 0; i+1 < len(parent.Content); {
+			suffix, ok := strings.CutPrefix(parent.Content[i].Value, key+".")
+			index, err := strconv.Atoi(suffix)
+			if !ok || err != nil || index < 0 {
+				i += 2
+				continue
+			}
+			items = append(items, item{index: index, value: parent.Content[i+1]})
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		if lookupNode(parent, []string{key}) != nil {
+			return fmt.Errorf("could not parse config file: key %s is set both as a list and by index", strings.Join(f.path, "."))
+		}
+
+		slices.SortStableFunc(items, func(a, b item) int {
+			return a.index - b.index
+		})
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, it := range items {
+			list.Content = append(list.Content, it.value)
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, list)
+		return nil
+	})
+}
+
+// removeEmptyStrings removes the keys of string fields with empty values from a config file,
+// so the fields keep their current values.
+func (m *Manager) removeEmptyStrings(doc *yaml.Node) error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.value.Kind() != reflect.String {
+			return nil
+		}
+		node := lookupNode(doc, f.path)
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value != "" {
+			return nil
+		}
+		removeKey(parentNode(doc, f.path), f.path[len(f.path)-1])
+		return nil
+	})
+}
+
+// rewriteValues rewrites the values in a config file that yaml can't decode itself, namely values with units,
+// long durations, hex or base64 encoded byte slices, base64 encoded strings and bools written as 0 or 1 or as strings, to a form that it can.
+func (m *Manager) rewriteValues(doc *yaml.Node) error {
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		node := lookupNode(doc, f.path)
+		if node == nil || node.Kind != yaml.ScalarNode {
+			return nil
+		}
+		// Pointer fields are written like the values they point to.
+		t := f.value.Type()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if parse, ok := m.unitParsers[f.name]; ok {
+			v, err := parse(node.Value)
+			if err != nil {
+				return fmt.Errorf("could not parse config file: key %s: %w", strings.Join(f.path, "."), err)
+			}
+			// yaml would truncate fractions into integer fields.
+			if k := t.Kind(); (reflect.Int <= k && k <= reflect.Uint64) && v != math.Trunc(v) {
+				return fmt.Errorf("could not parse config file: key %s: %s is not a whole number", strings.Join(f.path, "."), node.Value)
+			}
+			node.Value = strconv.FormatFloat(v, 'f', -1, 64)
+			node.Tag = ""
+			node.Style = 0
+			return nil
+		}
+		switch {
+		case f.structField.Tag.Get("type") == "longduration":
+			d, err := parseLongDuration(node.Value)
+			if err != nil {
+				return fmt.Errorf("could not parse config file: key %s: %w", strings.Join(f.path, "."), err)
+			}
+			node.Value = d.String()
+		case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+			decoded, err := decodeBytes(f.structField.Tag.Get("encoding"), node.Value)
+			if err != nil {
+				return fmt.Errorf("could not parse config file: key %s: %w", strings.Join(f.path, "."), err)
+			}
+			// yaml decodes a list of numbers into a byte slice.
+			*node = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+			for _, b := range decoded {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(int(b))})
+			}
+		case t.Kind() == reflect.String && f.structField.Tag.Get("encoding") == "base64":
+			decoded, err := base64.StdEncoding.DecodeString(node.Value)
+			if err != nil {
+				return fmt.Errorf("could not parse config file: key %s: invalid base64", strings.Join(f.path, "."))
+			}
+			node.Value = string(decoded)
+			node.Tag = "!!str"
+			node.Style = 0
+		case t.Kind() == reflect.Bool:
+			// Leave values that aren't bools for yaml to report.
+			if b, err := strconv.ParseBool(node.Value); err == nil {
+				node.Value = strconv.FormatBool(b)
+				node.Tag = "!!bool"
+				node.Style = 0
+			}
+		}
+		return nil
+	})
+}
+
+// decodeBytes decodes a byte slice written in the given encoding, hex by default.
+func decodeBytes(encoding, s string) ([]byte, error) {
+	switch encoding {
+	case "", "hex":
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, errors.New("invalid hex")
+		}
+		return b, nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, errors.New("invalid base64")
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", encoding)
+}
+
+// applyTransforms runs the registered transforms on the current flag values.
+func (m *Manager) applyTransforms() error {
+	for name, fn := range m.transforms {
+		f := m.flags.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("could not transform flag %s: flag not found", name)
+		}
+		if isUnset(f.Value) {
+			continue
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			values := sv.GetSlice()
+			for i, v := range values {
+				values[i] = fn(v)
+			}
+			if err := sv.Replace(values); err != nil {
+				return fmt.Errorf("could not transform flag %s: %w", name, err)
+			}
+			continue
+		}
+		if strings.HasPrefix(f.Value.Type(), "stringTo") {
+			return fmt.Errorf("could not transform flag %s: unsupported type %s", name, f.Value.Type())
+		}
+		if err := setPlainString(f.Value, fn(plainString(f.Value))); err != nil {
+			return fmt.Errorf("could not transform flag %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// applyParsers runs the registered parsers on the current flag values.
+func (m *Manager) applyParsers() error {
+	for name, parse := range m.parsers {
+		f := m.flags.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("could not parse flag %s: flag not found", name)
+		}
+		if isUnset(f.Value) {
+			continue
+		}
+		values := []string{plainString(f.Value)}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			values = sv.GetSlice()
+		}
+		for _, v := range values {
+			if err := parse(v); err != nil {
+				return fmt.Errorf("could not parse flag %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// applyEmbeddedDefaults decodes the embedded config file into the target, like the config file.
+func (m *Manager) applyEmbeddedDefaults() error {
+	var isJSON bool
+	switch m.embeddedFormat {
+	case "yaml", "yml":
+	case "json":
+		isJSON = true
+	default:
+		return fmt.Errorf("could not load embedded defaults: unsupported format %q", m.embeddedFormat)
+	}
+	_, warnings, err := m.decodeFile(m.embedded, isJSON)
+	if err != nil {
+		return fmt.Errorf("could not load embedded defaults: %w", err)
+	}
+	for _, warning := range warnings {
+		m.warn(warning)
+	}
+	return nil
+}
+
+// applyDefaults copies the non-zero fields of the defaults into the target.
+func (m *Manager) applyDefaults() error {
+	target := reflect.ValueOf(m.target).Elem()
+	defaults := reflect.Indirect(reflect.ValueOf(m.defaults))
+	if !defaults.IsValid() {
+		return fmt.Errorf("could not apply defaults: expected %s, got nil", target.Type())
+	}
+	if defaults.Type() != target.Type() {
+		return fmt.Errorf("could not apply defaults: expected %s, got %s", target.Type(), defaults.Type())
+	}
+	// Work on a copy, since walkFields only visits settable fields.
+	defaultsCopy := reflect.New(defaults.Type()).Elem()
+	defaultsCopy.Set(defaults)
+
+	targetFields, err := leafFields(m.nameTag, m.normalize, target)
+	if err != nil {
+		return err
+	}
+	defaultFields, err := leafFields(m.nameTag, m.normalize, defaultsCopy)
+	if err != nil {
+		return err
+	}
+	for i, f := range targetFields {
+		if value := defaultFields[i].value; !value.IsZero() {
+			f.value.Set(value)
+		}
+	}
+	return nil
 }
 
 // genFlagSet reads the configuration and uses reflection to generate a corresponding flagset.
 // Takes an input pointer to bind flags directly to the element.
-func (m Manager) genFlagSet(nameTag string) error {
+func (m *Manager) genFlagSet(nameTag string) error {
 	v := reflect.ValueOf(m.target)
 
 	if v.Kind() != reflect.Ptr {
@@ -95,37 +1531,48 @@ func (m Manager) genFlagSet(nameTag string) error {
 		return fmt.Errorf("expected struct, got %s", v.Kind())
 	}
 
-	if err := processStruct(nameTag, m.flags, v, ""); err != nil {
+	if err := processStruct(nameTag, m.normalize, m.flags, v, ""); err != nil {
 		return err
 	}
 
-	return nil
+	for name, description := range m.descriptions {
+		if f := m.flags.Lookup(name); f != nil {
+			f.Usage = description
+		}
+	}
+
+	// List the choices in the help, after the descriptions that replace the usage.
+	return walkFields(m.nameTag, m.normalize, v, "", nil, func(f field) error {
+		if tag, ok := f.structField.Tag.Lookup("choices"); ok {
+			if fl := m.flags.Lookup(f.name); fl != nil {
+				fl.Usage = strings.TrimSpace(fmt.Sprintf("%s (one of: %s)", fl.Usage, strings.Join(splitChoices(tag), ", ")))
+			}
+		}
+		return nil
+	})
 }
 
 // processStruct recursively processes struct fields and adds flags
-func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix string) error {
-	t := v.Type()
+// If normalize is not nil, it's applied to each name tag, including those of nested structs.
+func processStruct(nameTag string, normalize func(string) string, fs *pflag.FlagSet, v reflect.Value, prefix string) error {
+	if nameTag == "" {
+		nameTag = "name"
+	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		fieldValue := v.Field(i)
+	// The plan only has the fields with a name tag, and its tag values.
+	for _, plan := range structPlan(v.Type(), nameTag) {
+		field := plan.structField
+		fieldValue := v.Field(plan.index)
 
 		// Skip un-settable fields
 		if !fieldValue.CanSet() {
 			continue
 		}
 
-		// Get the required tag values
-		if nameTag == "" {
-			nameTag = "name"
-		}
-		name := field.Tag.Get(nameTag)
-		short := field.Tag.Get("short")
-		description := field.Tag.Get("description")
+		name, short, description, layout := plan.name, plan.short, plan.description, plan.layout
 
-		// Skip fields without name tag
-		if name == "" {
-			continue
+		if normalize != nil {
+			name = normalize(name)
 		}
 
 		// Add prefix if present
@@ -134,20 +1581,64 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 			fullName = prefix + "." + name
 		}
 
+		// The default tag only applies to fields that have no value yet, e.g. from WithDefaults.
+		if defaultValue, ok := field.Tag.Lookup("default"); ok && fieldValue.IsZero() {
+			if err := setDefault(nameTag, field, fieldValue, defaultValue); err != nil {
+				return err
+			}
+		}
+
+		// Bind fields that implement pflag.Value directly
+		if value, ok := fieldValue.Addr().Interface().(pflag.Value); ok {
+			if fs.Lookup(fullName) != nil {
+				return fmt.Errorf("flag %s of field %s is already defined", fullName, field.Name)
+			}
+			fs.VarP(value, fullName, short, description)
+			if err := markFlag(fs, fullName, field.Tag); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Handle nested structs
-		if fieldValue.Kind() == reflect.Struct {
-			if err := processStruct(nameTag, fs, fieldValue, fullName); err != nil {
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			if err := processStruct(nameTag, normalize, fs, fieldValue, fullName); err != nil {
 				return err
 			}
 			continue
 		}
 
+		if fs.Lookup(fullName) != nil {
+			return fmt.Errorf("flag %s of field %s is already defined", fullName, field.Name)
+		}
+
 		// Get pointer to the field for *Var methods
 		fieldPtr := fieldValue.Addr().Interface()
 
 		switch fieldValue.Kind() {
+		case reflect.Interface:
+			if fieldValue.IsNil() {
+				return fmt.Errorf("unsupported field type interface for field %s: set a concrete default value or use a concrete type", field.Name)
+			}
+			value, noOptDefVal, err := newInterfaceValue(nameTag, fieldValue)
+			if err != nil {
+				return fmt.Errorf("unsupported field type interface for field %s: %w", field.Name, err)
+			}
+			f := fs.VarPF(value, fullName, short, description)
+			f.NoOptDefVal = noOptDefVal
+		case reflect.Pointer:
+			value, noOptDefVal, err := newPointerValue(nameTag, field, fieldValue)
+			if err != nil {
+				return fmt.Errorf("unsupported field type %s for field %s: %w", fieldValue.Type(), field.Name, err)
+			}
+			f := fs.VarPF(value, fullName, short, description)
+			f.NoOptDefVal = noOptDefVal
+		case reflect.Struct:
+			fs.VarP(newTimeValue(fieldPtr.(*time.Time), layout), fullName, short, description)
 		case reflect.String:
-			if short != "" {
+			if field.Tag.Get("encoding") == "base64" {
+				fs.VarP(newBase64StringValue(fieldPtr.(*string)), fullName, short, description)
+			} else if short != "" {
 				fs.StringVarP(fieldPtr.(*string), fullName, short, fieldValue.String(), description)
 			} else {
 				fs.StringVar(fieldPtr.(*string), fullName, fieldValue.String(), description)
@@ -178,7 +1669,9 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 			}
 		case reflect.Int64:
 			// Check if this is a time.Duration (which is an int64 alias)
-			if fieldValue.Type().String() == "time.Duration" {
+			if fieldValue.Type().String() == "time.Duration" && field.Tag.Get("type") == "longduration" {
+				fs.VarP(newLongDurationValue(fieldPtr.(*time.Duration)), fullName, short, description)
+			} else if fieldValue.Type().String() == "time.Duration" {
 				if short != "" {
 					fs.DurationVarP(fieldPtr.(*time.Duration), fullName, short, time.Duration(fieldValue.Int()), description)
 				} else {
@@ -227,6 +1720,14 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 			} else {
 				fs.BoolVar(fieldPtr.(*bool), fullName, fieldValue.Bool(), description)
 			}
+			if field.Tag.Get("negatable") == "true" {
+				negatedName := "no-" + fullName
+				if fs.Lookup(negatedName) != nil {
+					return fmt.Errorf("flag %s of field %s is already defined", negatedName, field.Name)
+				}
+				f := fs.VarPF(newNegatedBoolValue(fieldPtr.(*bool)), negatedName, "", fmt.Sprintf("disable --%s", fullName))
+				f.NoOptDefVal = "true"
+			}
 		case reflect.Float32:
 			if short != "" {
 				fs.Float32VarP(fieldPtr.(*float32), fullName, short, float32(fieldValue.Float()), description)
@@ -241,7 +1742,20 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 			}
 		case reflect.Slice:
 			switch fieldValue.Type().Elem().Kind() {
+			case reflect.Uint8:
+				switch field.Tag.Get("encoding") {
+				case "", "hex":
+					fs.BytesHexVarP(fieldPtr.(*[]byte), fullName, short, fieldValue.Bytes(), description)
+				case "base64":
+					fs.BytesBase64VarP(fieldPtr.(*[]byte), fullName, short, fieldValue.Bytes(), description)
+				default:
+					return fmt.Errorf("unsupported encoding %s for field %s", field.Tag.Get("encoding"), field.Name)
+				}
 			case reflect.String:
+				if fieldValue.Type().Elem() != reflect.TypeOf("") {
+					fs.VarP(newStringsValue(fieldValue.Addr()), fullName, short, description)
+					break
+				}
 				defaultValue := make([]string, fieldValue.Len())
 				for j := 0; j < fieldValue.Len(); j++ {
 					defaultValue[j] = fieldValue.Index(j).String()
@@ -261,6 +1775,20 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 				} else {
 					fs.IntSliceVar(fieldPtr.(*[]int), fullName, defaultValue, description)
 				}
+			case reflect.Struct:
+				switch {
+				case fieldValue.Type().Elem() == timeType:
+					fs.VarP(newTimeSliceValue(fieldPtr.(*[]time.Time), layout), fullName, short, description)
+				case isPairStruct(fieldValue.Type().Elem()):
+					fs.VarP(newPairSliceValue(fieldValue.Addr()), fullName, short, description)
+				default:
+					// Flags can't express a variable number of other structs, so only the elements the slice has
+					// get indexed flags, e.g. endpoints.0.host. The config file can set any number.
+					if err := addElementFlags(nameTag, normalize, fs, fieldValue, fullName); err != nil {
+						return err
+					}
+					continue
+				}
 			default:
 				return fmt.Errorf("unsupported slice type %s for field %s", fieldValue.Type(), field.Name)
 			}
@@ -280,30 +1808,119 @@ func processStruct(nameTag string, fs *pflag.FlagSet, v reflect.Value, prefix st
 			} else if fieldValue.Type().Key().Kind() == reflect.String &&
 				fieldValue.Type().Elem().Kind() == reflect.Slice &&
 				fieldValue.Type().Elem().Elem().Kind() == reflect.String {
-				defaultValue := make(map[string][]string)
-				if !fieldValue.IsNil() {
-					for _, key := range fieldValue.MapKeys() {
-						val := fieldValue.MapIndex(key)
-						slice := make([]string, val.Len())
-						for j := 0; j < val.Len(); j++ {
-							slice[j] = val.Index(j).String()
-						}
-						defaultValue[key.String()] = slice
-					}
-				}
-				// Note: pflag does not have a built-in StringToStringSlice type,
-				// so map[string][]string fields will be populated from the config file only.
-				_ = defaultValue // Suppress unused variable warning
-				_ = fullName
-				_ = short
-				_ = description
+				// Values of a flag would need to separate the keys and the elements of their lists, so these fields
+				// are populated from the config file only.
+				continue
+			} else if fieldValue.Type().Key().Kind() == reflect.String && fieldValue.Type().Elem() == timeType {
+				fs.VarP(newTimeMapValue(fieldPtr.(*map[string]time.Time), layout), fullName, short, description)
+			} else if fieldValue.Type().Key().Kind() == reflect.String &&
+				fieldValue.Type().Elem().Kind() == reflect.Struct {
+				// Flags can't express a map of structs, so these fields are populated from the config file only.
+				continue
 			} else {
 				return fmt.Errorf("unsupported map type %s for field %s", fieldValue.Type(), field.Name)
 			}
 		default:
 			return fmt.Errorf("unsupported field type %s for field %s", fieldValue.Kind(), field.Name)
 		}
+
+		if err := markFlag(fs, fullName, field.Tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addElementFlags adds the flags of the struct fields of every element of slice, prefixed with the element's index.
+func addElementFlags(nameTag string, normalize func(string) string, fs *pflag.FlagSet, slice reflect.Value, prefix string) error {
+	for i := 0; i < slice.Len(); i++ {
+		holder := reflect.New(slice.Type().Elem()).Elem()
+		holder.Set(slice.Index(i))
+		elementFlags := pflag.NewFlagSet("element", pflag.ContinueOnError)
+		if err := processStruct(nameTag, normalize, elementFlags, holder, fmt.Sprintf("%s.%d", prefix, i)); err != nil {
+			return err
+		}
+		var err error
+		elementFlags.VisitAll(func(f *pflag.Flag) {
+			if err != nil {
+				return
+			}
+			if fs.Lookup(f.Name) != nil {
+				err = fmt.Errorf("flag %s is already defined", f.Name)
+				return
+			}
+			// Shorthands would be the same for every element, so they're left out.
+			fs.AddFlag(&pflag.Flag{
+				Name:        f.Name,
+				Usage:       f.Usage,
+				Value:       newElementValue(slice, i, holder, f.Value),
+				DefValue:    f.DefValue,
+				NoOptDefVal: f.NoOptDefVal,
+				Deprecated:  f.Deprecated,
+				Hidden:      f.Hidden,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setDefault parses the default tag s of a field like its flag and sets the field to it.
+func setDefault(nameTag string, field reflect.StructField, fieldValue reflect.Value, s string) error {
+	value, f, err := fieldFlag(nameTag, field, field.Type, reflect.Value{})
+	if err != nil {
+		return fmt.Errorf("invalid default for field %s: %w", field.Name, err)
+	}
+	if f == nil {
+		return fmt.Errorf("invalid default for field %s: fields without a flag can't have a default tag", field.Name)
+	}
+	if err := f.Value.Set(s); err != nil {
+		return fmt.Errorf("invalid default %q for field %s: %w", s, field.Name, err)
+	}
+	fieldValue.Set(value)
+	return nil
+}
+
+// fieldFlag generates the flag of a field of type t, with the tags of field that affect parsing,
+// on a single field struct set to initial if it's valid. It returns the field of the struct and its flag,
+// which is nil if the field has none.
+func fieldFlag(nameTag string, field reflect.StructField, t reflect.Type, initial reflect.Value) (reflect.Value, *pflag.Flag, error) {
+	tag := fmt.Sprintf(`%s:"value"`, nameTag)
+	for _, key := range []string{"layout", "type", "encoding"} {
+		if value, ok := field.Tag.Lookup(key); ok {
+			tag += fmt.Sprintf(` %s:%q`, key, value)
+		}
+	}
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: t,
+		Tag:  reflect.StructTag(tag),
+	}})).Elem()
+	if initial.IsValid() {
+		holder.Field(0).Set(initial)
 	}
+	fs := pflag.NewFlagSet("field", pflag.ContinueOnError)
+	if err := processStruct(nameTag, nil, fs, holder, ""); err != nil {
+		return reflect.Value{}, nil, err
+	}
+	return holder.Field(0), fs.Lookup("value"), nil
+}
 
+// markFlag marks a flag as deprecated or hidden according to the tags of its field.
+func markFlag(fs *pflag.FlagSet, name string, tag reflect.StructTag) error {
+	if fs.Lookup(name) == nil {
+		return nil
+	}
+	if replacement := tag.Get("deprecated"); replacement != "" {
+		if err := fs.MarkDeprecated(name, fmt.Sprintf("use --%s instead", replacement)); err != nil {
+			return err
+		}
+	}
+	if tag.Get("hidden") == "true" {
+		return fs.MarkHidden(name)
+	}
 	return nil
 }
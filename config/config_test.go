@@ -4,15 +4,26 @@
 package config
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
 )
 
 // Test structs with various field types
@@ -70,6 +81,18 @@ func createTempConfigFile(t *testing.T, content string) string {
 	return configPath
 }
 
+func parseWithArgs(t *testing.T, manager *Manager, configPath string, args []string) error {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().AddFlagSet(manager.FlagSet())
+
+	allArgs := append([]string{"--config", configPath}, args...)
+	if err := cmd.ParseFlags(allArgs); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	return manager.ParseConfiguration(cmd)
+}
+
 func TestNew(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -525,6 +548,72 @@ metadata:
 	}
 }
 
+func TestManagerParseConfigurationJSON(t *testing.T) {
+	// Files written by encoding/json use the field names as keys.
+	written := ComplexConfig{
+		Basic:    BasicInfo{Name: "test-basic", Version: "1.0.0"},
+		Server:   ServerConfig{Host: "localhost", Port: 8080},
+		Tags:     []string{"tag1", "tag2"},
+		Metadata: map[string]string{"key1": "value1", "key2": "value2"},
+	}
+	configData, err := json.Marshal(written)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, configData, 0644); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+
+	config := &ComplexConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := parseWithArgs(t, manager, configPath, []string{"--server.port", "9090"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	expected := written
+	expected.Server.Port = 9090
+	if !reflect.DeepEqual(*config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, *config)
+	}
+
+	// Json tags name the keys, and yaml keys keep working.
+	type JSONServer struct {
+		ListenAddr string `name:"listen-addr" json:"listen_addr"`
+		Port       int    `name:"port"`
+	}
+	type JSONConfig struct {
+		Server  JSONServer `name:"server" json:"srv"`
+		Ignored string     `name:"ignored" json:"-"`
+	}
+	if err := os.WriteFile(configPath, []byte(`{"srv": {"listen_addr": ":80", "PORT": 80}, "Ignored": "x", "ignored": "y"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	tagged := &JSONConfig{}
+	taggedManager, err := New(tagged, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := taggedManager.LoadFile(configPath); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if expected := (JSONConfig{Server: JSONServer{ListenAddr: ":80", Port: 80}}); *tagged != expected {
+		t.Errorf("Expected config %+v, got %+v", expected, *tagged)
+	}
+
+	// YAML in a .json file is an error.
+	if err := os.WriteFile(configPath, []byte("basic:\n  name: yaml\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	err = manager.LoadFile(configPath)
+	if err == nil || !strings.Contains(err.Error(), "could not parse config file: invalid character") {
+		t.Errorf("Expected JSON error, got: %v", err)
+	}
+}
+
 func TestProcessStructEdgeCases(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -552,7 +641,7 @@ func TestProcessStructEdgeCases(t *testing.T) {
 			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 			v := reflect.ValueOf(tt.input).Elem()
 
-			err := processStruct(tt.nameTag, flags, v, "")
+			err := processStruct(tt.nameTag, nil, flags, v, "")
 
 			if tt.expectError {
 				if err == nil {
@@ -600,7 +689,7 @@ func TestProcessStructAllTypes(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -636,7 +725,7 @@ func TestProcessStructUnsupportedSlice(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err == nil {
 		t.Error("Expected error for unsupported slice type")
 	}
@@ -645,6 +734,143 @@ func TestProcessStructUnsupportedSlice(t *testing.T) {
 	}
 }
 
+func TestParseConfigurationStructSlices(t *testing.T) {
+	type Header struct {
+		Name  string
+		Value string
+	}
+	type Listener struct {
+		Address string
+		Port    int
+	}
+	type StructSliceConfig struct {
+		Headers   []Header   `name:"headers" description:"HTTP headers"`
+		Listeners []Listener `name:"listeners"`
+	}
+
+	configData := `
+headers:
+  - name: Accept
+    value: text/plain
+listeners:
+  - address: localhost
+    port: 8080
+`
+	for _, test := range []struct {
+		Name     string
+		CmdArgs  []string
+		Expected []Header
+	}{
+		{
+			Name:     "FromConfigFile",
+			Expected: []Header{{Name: "Accept", Value: "text/plain"}},
+		},
+		{
+			Name:     "FromRepeatedFlag",
+			CmdArgs:  []string{"--headers", "X-Id=1", "--headers", "X-Query=a=b"},
+			Expected: []Header{{Name: "X-Id", Value: "1"}, {Name: "X-Query", Value: "a=b"}},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &StructSliceConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if manager.FlagSet().Lookup("listeners") != nil {
+				t.Error("Expected no flag for a slice of structs with other fields")
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(config.Headers, test.Expected) {
+				t.Errorf("Expected headers %+v, got %+v", test.Expected, config.Headers)
+			}
+			if expected := []Listener{{Address: "localhost", Port: 8080}}; !reflect.DeepEqual(config.Listeners, expected) {
+				t.Errorf("Expected listeners %+v, got %+v", expected, config.Listeners)
+			}
+		})
+	}
+
+	manager, err := New(&StructSliceConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.FlagSet().Set("headers", "invalid"); err == nil || !strings.Contains(err.Error(), "key=value") {
+		t.Errorf("Expected key=value error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationIndexedStructFlags(t *testing.T) {
+	type Endpoint struct {
+		Host string `name:"host" description:"Endpoint host"`
+		Port int    `name:"port" description:"Endpoint port"`
+	}
+	type EndpointConfig struct {
+		Endpoints []Endpoint `name:"endpoints"`
+	}
+	defaults := []Endpoint{{Host: "a.example.com", Port: 80}, {Host: "b.example.com", Port: 81}}
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Expected   []Endpoint
+	}{
+		{
+			Name:     "Defaults",
+			Expected: []Endpoint{{Host: "a.example.com", Port: 80}, {Host: "b.example.com", Port: 81}},
+		},
+		{
+			Name:     "FromFlags",
+			CmdArgs:  []string{"--endpoints.1.host", "c.example.com", "--endpoints.0.port", "8080"},
+			Expected: []Endpoint{{Host: "a.example.com", Port: 8080}, {Host: "c.example.com", Port: 81}},
+		},
+		{
+			Name:       "FlagsOverrideFile",
+			ConfigData: "endpoints:\n  - host: file.example.com\n    port: 443\n",
+			CmdArgs:    []string{"--endpoints.0.port", "8443", "--endpoints.1.host", "c.example.com"},
+			Expected:   []Endpoint{{Host: "file.example.com", Port: 8443}, {Host: "c.example.com"}},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &EndpointConfig{Endpoints: slices.Clone(defaults)}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(config.Endpoints, test.Expected) {
+				t.Errorf("Expected endpoints %+v, got %+v", test.Expected, config.Endpoints)
+			}
+		})
+	}
+
+	manager, err := New(&EndpointConfig{Endpoints: slices.Clone(defaults)}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	for name, expected := range map[string]string{
+		"endpoints.0.host": "a.example.com",
+		"endpoints.0.port": "80",
+		"endpoints.1.host": "b.example.com",
+		"endpoints.1.port": "81",
+	} {
+		if f := manager.FlagSet().Lookup(name); f == nil || f.DefValue != expected {
+			t.Errorf("Expected default of %s to be '%s', got %v", name, expected, f)
+		}
+	}
+	if manager.FlagSet().Lookup("endpoints.2.host") != nil {
+		t.Error("Expected no flags beyond the length of the slice")
+	}
+}
+
 // Test unsupported map types
 func TestProcessStructUnsupportedMap(t *testing.T) {
 	type UnsupportedMapConfig struct {
@@ -655,7 +881,7 @@ func TestProcessStructUnsupportedMap(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err == nil {
 		t.Error("Expected error for unsupported map type")
 	}
@@ -676,7 +902,7 @@ func TestProcessStructEmptyNameTag(t *testing.T) {
 	v := reflect.ValueOf(config).Elem()
 
 	// Test with empty nameTag - should default to "name"
-	err := processStruct("", flags, v, "")
+	err := processStruct("", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -702,7 +928,7 @@ func TestProcessStructSliceDefaults(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -734,7 +960,7 @@ func TestProcessStructMapDefaults(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -755,7 +981,7 @@ func TestProcessStructNilMap(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -789,7 +1015,7 @@ func TestProcessStructNestedWithPrefix(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "parent")
+	err := processStruct("name", nil, flags, v, "parent")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -814,7 +1040,7 @@ func TestProcessStructUnexportedFields(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -845,7 +1071,7 @@ func TestProcessStructIntegerTypesWithShortFlags(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -870,7 +1096,7 @@ func TestProcessStructUnsupportedInterface(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err == nil {
 		t.Error("Expected error for interface{} type")
 	}
@@ -895,7 +1121,7 @@ func TestProcessStructDeeplyNested(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -915,7 +1141,7 @@ func TestProcessStructMapIntValues(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err == nil {
 		t.Error("Expected error for map with non-string values")
 	}
@@ -931,7 +1157,7 @@ func TestProcessStructMapIntKeys(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err == nil {
 		t.Error("Expected error for map with non-string keys")
 	}
@@ -998,7 +1224,7 @@ func TestProcessStructDurationTypes(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1068,7 +1294,7 @@ func TestProcessStructWithPrefixAndEmptyName(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "prefix")
+	err := processStruct("name", nil, flags, v, "prefix")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1168,7 +1394,7 @@ func TestProcessStructComprehensiveCoverage(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1257,7 +1483,7 @@ func TestProcessStructIntSlice(t *testing.T) {
 			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 			v := reflect.ValueOf(test.Config).Elem()
 
-			err := processStruct("name", flags, v, "")
+			err := processStruct("name", nil, flags, v, "")
 			if err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
@@ -1296,7 +1522,7 @@ func TestIntSliceInComplexConfig(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1380,7 +1606,7 @@ func TestProcessStructStringToStringSliceMap(t *testing.T) {
 			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 			v := reflect.ValueOf(test.Config).Elem()
 
-			err := processStruct("name", flags, v, "")
+			err := processStruct("name", nil, flags, v, "")
 			if err != nil {
 				t.Errorf("Unexpected error: %v", err)
 			}
@@ -1424,7 +1650,7 @@ func TestStringToStringSliceMapInComplexConfig(t *testing.T) {
 	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
 	v := reflect.ValueOf(config).Elem()
 
-	err := processStruct("name", flags, v, "")
+	err := processStruct("name", nil, flags, v, "")
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -1694,3 +1920,2937 @@ ports: []
 		})
 	}
 }
+
+func TestRegisterTransform(t *testing.T) {
+	type TransformConfig struct {
+		Name  string   `name:"name" description:"App name"`
+		Host  string   `name:"host" description:"Host name"`
+		Tags  []string `name:"tags" description:"Tags"`
+		Other string   `name:"other" description:"Untransformed"`
+	}
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Validate   func(t *testing.T, config *TransformConfig)
+	}{
+		{
+			Name: "TransformsConfigValues",
+			ConfigData: `
+name: "MyApp"
+host: "EXAMPLE.com"
+tags: ["A", "b"]
+other: "Mixed"
+`,
+			Validate: func(t *testing.T, config *TransformConfig) {
+				if config.Name != "myapp" {
+					t.Errorf("Expected name 'myapp', got '%s'", config.Name)
+				}
+				if config.Host != "example.com" {
+					t.Errorf("Expected host 'example.com', got '%s'", config.Host)
+				}
+				if len(config.Tags) != 2 || config.Tags[0] != "a" || config.Tags[1] != "b" {
+					t.Errorf("Expected tags [a b], got %v", config.Tags)
+				}
+				if config.Other != "Mixed" {
+					t.Errorf("Expected other 'Mixed', got '%s'", config.Other)
+				}
+			},
+		},
+		{
+			Name: "TransformsFlagValues",
+			ConfigData: `
+name: "from-config"
+`,
+			CmdArgs: []string{"--name", "FromFlag", "--host", "Flag.Example.COM"},
+			Validate: func(t *testing.T, config *TransformConfig) {
+				if config.Name != "fromflag" {
+					t.Errorf("Expected name 'fromflag', got '%s'", config.Name)
+				}
+				if config.Host != "flag.example.com" {
+					t.Errorf("Expected host 'flag.example.com', got '%s'", config.Host)
+				}
+			},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &TransformConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			manager.RegisterTransform("name", strings.ToLower)
+			manager.RegisterTransform("host", strings.ToLower)
+			manager.RegisterTransform("tags", strings.ToLower)
+
+			configPath := createTempConfigFile(t, test.ConfigData)
+			if err := parseWithArgs(t, manager, configPath, test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+
+			test.Validate(t, config)
+		})
+	}
+}
+
+func TestRegisterTransformUnknownFlag(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.RegisterTransform("missing", strings.ToLower)
+
+	configPath := createTempConfigFile(t, `name: "test"`)
+	err = parseWithArgs(t, manager, configPath, nil)
+	if err == nil || !strings.Contains(err.Error(), "flag not found") {
+		t.Errorf("Expected flag not found error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationDeprecatedKeys(t *testing.T) {
+	type DeprecatedServer struct {
+		Host    string `name:"host" description:"Server host"`
+		Address string `name:"address" description:"Server address" deprecated:"server.host"`
+	}
+	type DeprecatedConfig struct {
+		Name   string           `name:"name" description:"App name"`
+		Server DeprecatedServer `name:"server"`
+	}
+
+	for _, test := range []struct {
+		Name             string
+		ConfigData       string
+		ExpectedWarnings []string
+	}{
+		{
+			Name: "DeprecatedKeyPresent",
+			ConfigData: `
+name: "test"
+server:
+  address: "localhost"
+`,
+			ExpectedWarnings: []string{`config key "server.address" is deprecated, use "server.host" instead`},
+		},
+		{
+			Name: "DeprecatedKeyAbsent",
+			ConfigData: `
+name: "test"
+server:
+  host: "localhost"
+`,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			var warnings []string
+			config := &DeprecatedConfig{}
+			manager, err := New(config, "", WithWarningHandler(func(msg string) {
+				warnings = append(warnings, msg)
+			}))
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			configPath := createTempConfigFile(t, test.ConfigData)
+			if err := parseWithArgs(t, manager, configPath, nil); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+
+			if !reflect.DeepEqual(warnings, test.ExpectedWarnings) {
+				t.Errorf("Expected warnings %v, got %v", test.ExpectedWarnings, warnings)
+			}
+
+			f := manager.FlagSet().Lookup("server.address")
+			if f == nil || f.Deprecated != "use --server.host instead" {
+				t.Errorf("Expected server.address flag to be deprecated, got %+v", f)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationSetFlag(t *testing.T) {
+	configData := `
+basic:
+  name: "from-config"
+  version: "1.0.0"
+server:
+  host: "config-host"
+  port: 8080
+`
+	for _, test := range []struct {
+		Name        string
+		CmdArgs     []string
+		ExpectError string
+		Validate    func(t *testing.T, config *ComplexConfig)
+	}{
+		{
+			Name:    "SetsNestedFields",
+			CmdArgs: []string{"--set", "server.host=localhost", "--set", "server.port=9090", "--set", "tags=a,b"},
+			Validate: func(t *testing.T, config *ComplexConfig) {
+				if config.Server.Host != "localhost" {
+					t.Errorf("Expected server.host 'localhost', got '%s'", config.Server.Host)
+				}
+				if config.Server.Port != 9090 {
+					t.Errorf("Expected server.port 9090, got %d", config.Server.Port)
+				}
+				if len(config.Tags) != 2 || config.Tags[0] != "a" || config.Tags[1] != "b" {
+					t.Errorf("Expected tags [a b], got %v", config.Tags)
+				}
+				if config.Basic.Version != "1.0.0" {
+					t.Errorf("Expected basic.version '1.0.0' from config, got '%s'", config.Basic.Version)
+				}
+			},
+		},
+		{
+			Name:    "FlagsOverrideSet",
+			CmdArgs: []string{"--set", "basic.name=from-set", "--basic.name", "from-flag"},
+			Validate: func(t *testing.T, config *ComplexConfig) {
+				if config.Basic.Name != "from-flag" {
+					t.Errorf("Expected basic.name 'from-flag', got '%s'", config.Basic.Name)
+				}
+			},
+		},
+		{
+			Name:        "UnknownFlag",
+			CmdArgs:     []string{"--set", "server.missing=1"},
+			ExpectError: "unknown flag server.missing",
+		},
+		{
+			Name:        "InvalidValue",
+			CmdArgs:     []string{"--set", "server.port=not-a-number"},
+			ExpectError: "could not apply --set server.port=not-a-number",
+		},
+		{
+			Name:        "MissingAssignment",
+			CmdArgs:     []string{"--set", "server.port"},
+			ExpectError: "expected name=value",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ComplexConfig{}
+			manager, err := New(config, "", WithSetFlag())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			configPath := createTempConfigFile(t, configData)
+			err = parseWithArgs(t, manager, configPath, test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.ExpectError) {
+					t.Errorf("Expected error containing '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+
+			test.Validate(t, config)
+		})
+	}
+}
+
+func TestSourceOf(t *testing.T) {
+	configData := `
+basic:
+  name: "from-config"
+server:
+  host: "config-host"
+  port: 8080
+`
+	config := &ComplexConfig{}
+	manager, err := New(config, "", WithSourceTracking(), WithSetFlag())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, configData)
+	args := []string{"--server.host", "flag-host", "--set", "basic.version=2.0.0"}
+	if err := parseWithArgs(t, manager, configPath, args); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"basic.name":    "file",
+		"basic.version": "flag",
+		"server.host":   "flag",
+		"server.port":   "file",
+		"tags":          "default",
+		"missing":       "",
+	} {
+		if source := manager.SourceOf(name); source != expected {
+			t.Errorf("Expected source of %s to be '%s', got '%s'", name, expected, source)
+		}
+	}
+}
+
+func TestSourceOfWithoutTracking(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, `name: "test"`)
+	if err := parseWithArgs(t, manager, configPath, nil); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	if source := manager.SourceOf("name"); source != "" {
+		t.Errorf("Expected no source without tracking, got '%s'", source)
+	}
+}
+
+func TestParseConfigurationMapOfStructs(t *testing.T) {
+	type ConfigWithServers struct {
+		Name    string                  `name:"name" description:"App name"`
+		Servers map[string]ServerConfig `name:"servers" description:"Named servers"`
+	}
+
+	config := &ConfigWithServers{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if manager.FlagSet().Lookup("servers") != nil {
+		t.Error("Expected no flag for map of structs")
+	}
+
+	configData := `
+name: "test"
+servers:
+  a:
+    host: "a.example.com"
+    port: 8080
+  b:
+    host: "b.example.com"
+    port: 9090
+`
+	configPath := createTempConfigFile(t, configData)
+	if err := parseWithArgs(t, manager, configPath, []string{"--name", "from-flag"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	expected := map[string]ServerConfig{
+		"a": {Host: "a.example.com", Port: 8080},
+		"b": {Host: "b.example.com", Port: 9090},
+	}
+	if !reflect.DeepEqual(config.Servers, expected) {
+		t.Errorf("Expected servers %v, got %v", expected, config.Servers)
+	}
+	if config.Name != "from-flag" {
+		t.Errorf("Expected name 'from-flag', got '%s'", config.Name)
+	}
+}
+
+func TestWithDescriptions(t *testing.T) {
+	config := &ComplexConfig{}
+	manager, err := New(config, "", WithDescriptions(map[string]string{
+		"server.host": "Hôte du serveur",
+		"tags":        "Étiquettes",
+		"missing":     "Ignored",
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"server.host": "Hôte du serveur",
+		"tags":        "Étiquettes",
+		"server.port": "Server port",
+	} {
+		f := manager.FlagSet().Lookup(name)
+		if f == nil {
+			t.Errorf("Expected flag %s", name)
+			continue
+		}
+		if f.Usage != expected {
+			t.Errorf("Expected usage of %s to be '%s', got '%s'", name, expected, f.Usage)
+		}
+	}
+}
+
+func TestProcessStructTimeSliceDefaults(t *testing.T) {
+	type ConfigWithTimes struct {
+		Checkpoints []time.Time `name:"checkpoints" description:"Checkpoints"`
+	}
+
+	checkpoint := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	config := &ConfigWithTimes{Checkpoints: []time.Time{checkpoint}}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	flag := manager.FlagSet().Lookup("checkpoints")
+	if flag == nil {
+		t.Fatal("Expected flag checkpoints")
+	}
+	if flag.Value.Type() != "timeSlice" {
+		t.Errorf("Expected type timeSlice, got %s", flag.Value.Type())
+	}
+	if flag.DefValue != "[2026-01-02T03:04:05Z]" {
+		t.Errorf("Expected default '[2026-01-02T03:04:05Z]', got '%s'", flag.DefValue)
+	}
+}
+
+func TestParseConfigurationWithTimes(t *testing.T) {
+	type ConfigWithTimes struct {
+		Checkpoints []time.Time          `name:"checkpoints" description:"Checkpoints"`
+		Dates       []time.Time          `name:"dates" description:"Dates" layout:"2006-01-02"`
+		Deadlines   map[string]time.Time `name:"deadlines" description:"Deadlines"`
+	}
+
+	config := &ConfigWithTimes{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configData := `
+checkpoints:
+  - 2026-01-01T00:00:00Z
+dates:
+  - 2026-01-01T00:00:00Z
+deadlines:
+  alpha: 2026-03-01T12:00:00Z
+`
+	configPath := createTempConfigFile(t, configData)
+	args := []string{
+		"--checkpoints", "2026-02-01T00:00:00Z,2026-02-02T00:00:00Z",
+		"--dates", "2026-05-01",
+		"--deadlines", "beta=2026-04-01T12:00:00Z",
+	}
+	if err := parseWithArgs(t, manager, configPath, args); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	expectedCheckpoints := []time.Time{
+		time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(config.Checkpoints, expectedCheckpoints) {
+		t.Errorf("Expected checkpoints %v, got %v", expectedCheckpoints, config.Checkpoints)
+	}
+	expectedDates := []time.Time{time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(config.Dates, expectedDates) {
+		t.Errorf("Expected dates %v, got %v", expectedDates, config.Dates)
+	}
+	expectedDeadlines := map[string]time.Time{
+		"alpha": time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		"beta":  time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(config.Deadlines, expectedDeadlines) {
+		t.Errorf("Expected deadlines %v, got %v", expectedDeadlines, config.Deadlines)
+	}
+}
+
+func TestWithNameNormalizer(t *testing.T) {
+	type Listener struct {
+		HostName string `name:"Host_Name" description:"Host name"`
+	}
+	type ConfigWithMixedCase struct {
+		LogLevel string   `name:"LogLevel" description:"Log level"`
+		Listener Listener `name:"HTTP_Listener"`
+	}
+	normalize := func(name string) string {
+		return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+
+	manager, err := New(&ConfigWithMixedCase{}, "", WithNameNormalizer(normalize))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	for _, name := range []string{"loglevel", "http-listener.host-name"} {
+		if manager.FlagSet().Lookup(name) == nil {
+			t.Errorf("Expected flag %s", name)
+		}
+	}
+	if manager.FlagSet().Lookup("LogLevel") != nil {
+		t.Error("Expected no flag LogLevel")
+	}
+
+	type ConfigWithCollision struct {
+		Name  string `name:"Name" description:"Name"`
+		Alias string `name:"name" description:"Alias"`
+	}
+	_, err = New(&ConfigWithCollision{}, "", WithNameNormalizer(normalize))
+	if err == nil || !strings.Contains(err.Error(), "flag name of field Alias is already defined") {
+		t.Errorf("Expected collision error, got %v", err)
+	}
+}
+
+func TestResolutionReport(t *testing.T) {
+	configData := `
+basic:
+  name: "from-config"
+server:
+  port: 8080
+`
+	config := &ComplexConfig{}
+	manager, err := New(config, "", WithSourceTracking())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, configData)
+	if err := parseWithArgs(t, manager, configPath, []string{"--server.host", "flag-host"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	expected := []Resolution{
+		{Flag: "basic.name", Source: "file", Value: "from-config", Overridden: true},
+		{Flag: "basic.version", Source: "default", Value: "", Overridden: false},
+		{Flag: "metadata", Source: "default", Value: "[]", Overridden: false},
+		{Flag: "server.host", Source: "flag", Value: "flag-host", Overridden: true},
+		{Flag: "server.port", Source: "file", Value: "8080", Overridden: true},
+		{Flag: "tags", Source: "default", Value: "[]", Overridden: false},
+	}
+	if report := manager.ResolutionReport(); !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected report %+v, got %+v", expected, report)
+	}
+
+	untracked, err := New(&SimpleConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if report := untracked.ResolutionReport(); report != nil {
+		t.Errorf("Expected no report without tracking, got %+v", report)
+	}
+}
+
+func TestBindPersistent(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	root := &cobra.Command{Use: "root"}
+	manager.BindPersistent(root)
+	var ran bool
+	sub := &cobra.Command{
+		Use: "sub",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ran = true
+			if cmd.Flags().Lookup("config") == nil {
+				t.Error("Expected subcommand to inherit the config flag")
+			}
+			return manager.ParseConfiguration(cmd)
+		},
+	}
+	root.AddCommand(sub)
+
+	configPath := createTempConfigFile(t, "name: \"from-config\"\nport: 8080\n")
+	root.SetArgs([]string{"sub", "--config", configPath, "--port", "9090"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if !ran {
+		t.Fatal("Expected subcommand to run")
+	}
+	if config.Name != "from-config" {
+		t.Errorf("Expected name 'from-config', got '%s'", config.Name)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+}
+
+func TestParseConfigurationLongDuration(t *testing.T) {
+	type ConfigWithRetention struct {
+		Retention time.Duration `name:"retention" description:"Retention" type:"longduration"`
+		Grace     time.Duration `name:"grace" description:"Grace period" type:"longduration"`
+		Interval  time.Duration `name:"interval" description:"Interval" type:"longduration"`
+	}
+
+	config := &ConfigWithRetention{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configData := `
+retention: 1d
+grace: 1h
+interval: 5m
+`
+	configPath := createTempConfigFile(t, configData)
+	if err := parseWithArgs(t, manager, configPath, []string{"--grace", "2w", "--interval", "30s"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	for name, test := range map[string]struct {
+		got, expected time.Duration
+	}{
+		"retention": {config.Retention, 24 * time.Hour},
+		"grace":     {config.Grace, 14 * 24 * time.Hour},
+		"interval":  {config.Interval, 30 * time.Second},
+	} {
+		if test.got != test.expected {
+			t.Errorf("Expected %s to be %s, got %s", name, test.expected, test.got)
+		}
+	}
+
+	if err := manager.FlagSet().Set("retention", "1x"); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
+
+func TestUserFlagSet(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "", WithSetFlag())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	fs := manager.UserFlagSet()
+	for _, name := range []string{"name", "port", "debug", "timeout", "rate"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("Expected flag %s", name)
+		}
+	}
+	for _, name := range []string{"config", "set"} {
+		if fs.Lookup(name) != nil {
+			t.Errorf("Expected no flag %s", name)
+		}
+	}
+
+	if err := fs.Set("port", "9090"); err != nil {
+		t.Fatalf("Failed to set flag: %v", err)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+}
+
+func TestRegisterUnitParser(t *testing.T) {
+	type UnitConfig struct {
+		Rate  float64 `name:"rate" description:"Requests per second"`
+		Burst int     `name:"burst" description:"Burst per second"`
+	}
+	perSecond := func(s string) (float64, error) {
+		number, ok := strings.CutSuffix(s, "/s")
+		if !ok {
+			return 0, fmt.Errorf("%s is not a rate per second", s)
+		}
+		return strconv.ParseFloat(number, 64)
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		Expected    UnitConfig
+		ExpectError string
+	}{
+		{
+			Name:       "FromConfigFile",
+			ConfigData: "rate: 10/s\nburst: 20/s\n",
+			Expected:   UnitConfig{Rate: 10, Burst: 20},
+		},
+		{
+			Name:       "FromFlags",
+			ConfigData: `rate: 10/s`,
+			CmdArgs:    []string{"--rate", "2.5/s", "--burst", "5/s"},
+			Expected:   UnitConfig{Rate: 2.5, Burst: 5},
+		},
+		{
+			Name:        "InvalidConfigValue",
+			ConfigData:  `rate: 10`,
+			ExpectError: "could not parse config file: key rate: 10 is not a rate per second",
+		},
+		{
+			Name:        "FractionForInteger",
+			ConfigData:  `burst: 2.5/s`,
+			ExpectError: "could not parse config file: key burst: 2.5/s is not a whole number",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &UnitConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			manager.RegisterUnitParser("rate", perSecond)
+			manager.RegisterUnitParser("burst", perSecond)
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.ExpectError) {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if *config != test.Expected {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+
+	manager, err := New(&UnitConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.RegisterUnitParser("missing", perSecond)
+	err = parseWithArgs(t, manager, createTempConfigFile(t, ""), nil)
+	if err == nil || err.Error() != "could not parse units of flag missing: flag not found" {
+		t.Errorf("Expected flag not found error, got: %v", err)
+	}
+}
+
+func TestRegisterParser(t *testing.T) {
+	type ParserConfig struct {
+		Name     string   `name:"name" description:"App name"`
+		Networks []string `name:"networks" description:"Allowed networks"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		Expected    []string
+		ExpectError string
+	}{
+		{
+			Name: "ParsesConfigValues",
+			ConfigData: `
+networks: ["10.0.0.0/8", "192.168.0.0/16"]
+`,
+			Expected: []string{"10.0.0.0/8", "192.168.0.0/16"},
+		},
+		{
+			Name: "ParsesFlagValues",
+			ConfigData: `
+networks: ["10.0.0.0/8"]
+`,
+			CmdArgs:  []string{"--networks", "172.16.0.0/12"},
+			Expected: []string{"172.16.0.0/12"},
+		},
+		{
+			Name: "ReturnsConfigErrors",
+			ConfigData: `
+networks: ["10.0.0.0"]
+`,
+			ExpectError: "could not parse flag networks: invalid CIDR address: 10.0.0.0",
+		},
+		{
+			Name: "ReturnsFlagErrors",
+			ConfigData: `
+networks: ["10.0.0.0/8"]
+`,
+			CmdArgs:     []string{"--networks", "not-a-network"},
+			ExpectError: "could not parse flag networks: invalid CIDR address: not-a-network",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ParserConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			var parsed []string
+			manager.RegisterParser("networks", func(s string) error {
+				_, network, err := net.ParseCIDR(s)
+				if err != nil {
+					return err
+				}
+				parsed = append(parsed, network.String())
+				return nil
+			})
+
+			configPath := createTempConfigFile(t, test.ConfigData)
+			err = parseWithArgs(t, manager, configPath, test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(parsed, test.Expected) {
+				t.Errorf("Expected parsed networks %v, got %v", test.Expected, parsed)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationNegatableBool(t *testing.T) {
+	type NegatableConfig struct {
+		Cache bool `name:"cache" description:"Enable the cache" negatable:"true"`
+	}
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Expected   bool
+	}{
+		{
+			Name:     "Default",
+			Expected: true,
+		},
+		{
+			Name:       "PositiveFlag",
+			ConfigData: "cache: false",
+			CmdArgs:    []string{"--cache"},
+			Expected:   true,
+		},
+		{
+			Name:     "NegatedFlag",
+			CmdArgs:  []string{"--no-cache"},
+			Expected: false,
+		},
+		{
+			Name:     "NegatedFlagWithValue",
+			CmdArgs:  []string{"--no-cache=false"},
+			Expected: true,
+		},
+		{
+			Name:     "LastFlagWins",
+			CmdArgs:  []string{"--no-cache", "--cache"},
+			Expected: true,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &NegatableConfig{Cache: true}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			configPath := createTempConfigFile(t, test.ConfigData)
+			if err := parseWithArgs(t, manager, configPath, test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if config.Cache != test.Expected {
+				t.Errorf("Expected cache %v, got %v", test.Expected, config.Cache)
+			}
+		})
+	}
+}
+
+func TestManagerLoadFile(t *testing.T) {
+	config := &ComplexConfig{
+		Basic:  BasicInfo{Version: "1.0.0"},
+		Server: ServerConfig{Host: "localhost", Port: 8080},
+	}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configData := `
+basic:
+  name: "from-file"
+server:
+  port: 9090
+tags: ["a", "b"]
+`
+	if err := manager.LoadFile(createTempConfigFile(t, configData)); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	expected := &ComplexConfig{
+		Basic:  BasicInfo{Name: "from-file", Version: "1.0.0"},
+		Server: ServerConfig{Host: "localhost", Port: 9090},
+		Tags:   []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, config)
+	}
+
+	err = manager.LoadFile(filepath.Join(t.TempDir(), "missing.yml"))
+	if err == nil || !strings.Contains(err.Error(), "could not read config file") {
+		t.Errorf("Expected read error, got: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	configData := `
+basic:
+  name: "from-file"
+  version: "1.0.0"
+server:
+  host: "example.com"
+  port: 8080
+tags: ["a", "b"]
+metadata:
+  env: "test"
+`
+	config := &ComplexConfig{}
+	args := []string{"--config", createTempConfigFile(t, configData), "--server.port", "9090"}
+	if err := Load(config, args); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	expected := &ComplexConfig{
+		Basic:    BasicInfo{Name: "from-file", Version: "1.0.0"},
+		Server:   ServerConfig{Host: "example.com", Port: 9090},
+		Tags:     []string{"a", "b"},
+		Metadata: map[string]string{"env": "test"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, config)
+	}
+
+	err := Load(&ComplexConfig{}, []string{"--config", filepath.Join(t.TempDir(), "missing.yml")})
+	if err == nil || !strings.Contains(err.Error(), "could not read config file") {
+		t.Errorf("Expected read error, got: %v", err)
+	}
+}
+
+func TestManagerUnmarshal(t *testing.T) {
+	type Endpoint struct {
+		Port int `yaml:"port"`
+	}
+	type Model struct {
+		Endpoint Endpoint `yaml:"server"`
+		Labels   []string `yaml:"tags"`
+	}
+
+	manager, err := New(&ComplexConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.Unmarshal(&Model{}); err == nil || !strings.Contains(err.Error(), "no config file loaded") {
+		t.Errorf("Expected error before loading, got: %v", err)
+	}
+
+	configData := `
+basic:
+  name: "from-file"
+server:
+  host: "example.com"
+  port: 9090
+tags: ["a", "b"]
+`
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), []string{"--server.port", "8080"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	var model Model
+	if err := manager.Unmarshal(&model); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	// Flags don't apply, so the port is the one from the file.
+	expected := Model{Endpoint: Endpoint{Port: 9090}, Labels: []string{"a", "b"}}
+	if !reflect.DeepEqual(model, expected) {
+		t.Errorf("Expected model %+v, got %+v", expected, model)
+	}
+}
+
+func TestWithTreatEmptyAsUnset(t *testing.T) {
+	configData := `
+basic:
+  name: ""
+server:
+  host: ""
+  port: 9090
+`
+	for _, test := range []struct {
+		Name     string
+		Options  []Option
+		Expected ComplexConfig
+	}{
+		{
+			Name:     "WithoutOption",
+			Expected: ComplexConfig{Basic: BasicInfo{Version: "1.0.0"}, Server: ServerConfig{Port: 9090}},
+		},
+		{
+			Name:    "WithOption",
+			Options: []Option{WithTreatEmptyAsUnset()},
+			Expected: ComplexConfig{
+				Basic:  BasicInfo{Name: "default", Version: "1.0.0"},
+				Server: ServerConfig{Host: "localhost", Port: 9090},
+			},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ComplexConfig{
+				Basic:  BasicInfo{Name: "default", Version: "1.0.0"},
+				Server: ServerConfig{Host: "localhost", Port: 8080},
+			}
+			manager, err := New(config, "", test.Options...)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), nil); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
+
+func TestWithYAMLDecoderOptions(t *testing.T) {
+	configData := `
+name: "test"
+unknown: "value"
+`
+	for _, test := range []struct {
+		Name        string
+		Options     []Option
+		ExpectError string
+	}{
+		{
+			Name: "IgnoresUnknownFieldsByDefault",
+		},
+		{
+			Name: "KnownFieldsRejectsUnknownFields",
+			Options: []Option{WithYAMLDecoderOptions(func(dec *yaml.Decoder) {
+				dec.KnownFields(true)
+			})},
+			ExpectError: "field unknown not found",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &SimpleConfig{}
+			manager, err := New(config, "", test.Options...)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = manager.LoadFile(createTempConfigFile(t, configData))
+			if test.ExpectError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.ExpectError) {
+					t.Errorf("Expected error containing '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile failed: %v", err)
+			}
+			if config.Name != "test" {
+				t.Errorf("Expected name 'test', got '%s'", config.Name)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	defaults := ComplexConfig{
+		Basic:  BasicInfo{Version: "1.0.0"},
+		Server: ServerConfig{Host: "localhost", Port: 8080},
+		Tags:   []string{"a", "b"},
+	}
+
+	config := &ComplexConfig{Basic: BasicInfo{Name: "app"}}
+	manager, err := New(config, "", WithDefaults(defaults))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"basic.name":    "app",
+		"basic.version": "1.0.0",
+		"server.host":   "localhost",
+		"server.port":   "8080",
+		"tags":          "[a,b]",
+		"metadata":      "[]",
+	} {
+		if f := manager.FlagSet().Lookup(name); f == nil || f.DefValue != expected {
+			t.Errorf("Expected default of %s to be '%s', got %v", name, expected, f)
+		}
+	}
+
+	_, err = New(&SimpleConfig{}, "", WithDefaults(&defaults))
+	if err == nil || !strings.Contains(err.Error(), "could not apply defaults") {
+		t.Errorf("Expected type mismatch error, got: %v", err)
+	}
+
+	_, err = New(&ComplexConfig{}, "", WithDefaults((*ComplexConfig)(nil)))
+	if err == nil || !strings.Contains(err.Error(), "could not apply defaults") {
+		t.Errorf("Expected nil defaults error, got: %v", err)
+	}
+}
+
+func TestWithEmbeddedDefaults(t *testing.T) {
+	embedded := []byte("name: embedded\nport: 8080\ntimeout: 5s\n")
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Expected   SimpleConfig
+	}{
+		{
+			Name:       "FileOverrides",
+			ConfigData: "port: 9090\n",
+			Expected:   SimpleConfig{Name: "embedded", Port: 9090, Timeout: 5 * time.Second},
+		},
+		{
+			Name:       "FlagOverrides",
+			ConfigData: "port: 9090\n",
+			CmdArgs:    []string{"--port", "7070", "--debug"},
+			Expected:   SimpleConfig{Name: "embedded", Port: 7070, Debug: true, Timeout: 5 * time.Second},
+		},
+		{
+			Name:     "NoFile",
+			CmdArgs:  []string{"--name", "flag"},
+			Expected: SimpleConfig{Name: "flag", Port: 8080, Timeout: 5 * time.Second},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &SimpleConfig{}
+			manager, err := New(config, "", WithEmbeddedDefaults(embedded, "yaml"))
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if f := manager.FlagSet().Lookup("port"); f.DefValue != "8080" {
+				t.Errorf("Expected default of port to be '8080', got '%s'", f.DefValue)
+			}
+
+			if test.ConfigData != "" {
+				err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			} else {
+				// Without --config, the default config file doesn't exist in the working directory.
+				t.Chdir(t.TempDir())
+				err = manager.ParseArgs(test.CmdArgs)
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+
+	manager, err := New(&SimpleConfig{}, "", WithEmbeddedDefaults(embedded, "yaml"))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	err = parseWithArgs(t, manager, filepath.Join(t.TempDir(), "typo.yml"), nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing config file set with --config to be an error, got: %v", err)
+	}
+
+	config := &SimpleConfig{}
+	if _, err := New(config, "", WithEmbeddedDefaults([]byte(`{"port": 8080}`), "json")); err != nil {
+		t.Fatalf("Failed to create manager with JSON defaults: %v", err)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Expected port 8080 from JSON defaults, got %d", config.Port)
+	}
+
+	_, err = New(&SimpleConfig{}, "", WithEmbeddedDefaults(embedded, "toml"))
+	if err == nil || !strings.Contains(err.Error(), `unsupported format "toml"`) {
+		t.Errorf("Expected unsupported format error, got: %v", err)
+	}
+}
+
+func TestDefaultTag(t *testing.T) {
+	type DefaultServer struct {
+		Host string `name:"host" description:"Server host" default:"localhost"`
+		Port int    `name:"port" description:"Server port" default:"8080"`
+	}
+	type DefaultConfig struct {
+		Name    string        `name:"name" description:"Name" default:"app"`
+		Debug   bool          `name:"debug" description:"Debug mode" default:"true"`
+		Timeout time.Duration `name:"timeout" description:"Timeout" default:"30s"`
+		Tags    []string      `name:"tags" description:"Tags" default:"a,b"`
+		Key     []byte        `name:"key" description:"Key" default:"cafe"`
+		Server  DefaultServer `name:"server"`
+	}
+
+	config := &DefaultConfig{Name: "preset"}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	expected := DefaultConfig{
+		Name:    "preset",
+		Debug:   true,
+		Timeout: 30 * time.Second,
+		Tags:    []string{"a", "b"},
+		Key:     []byte{0xca, 0xfe},
+		Server:  DefaultServer{Host: "localhost", Port: 8080},
+	}
+	if !reflect.DeepEqual(*config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, *config)
+	}
+	for name, expected := range map[string]string{
+		"name":        "preset",
+		"debug":       "true",
+		"timeout":     "30s",
+		"tags":        "[a,b]",
+		"key":         "CAFE",
+		"server.port": "8080",
+	} {
+		if f := manager.FlagSet().Lookup(name); f == nil || f.DefValue != expected {
+			t.Errorf("Expected default of %s to be '%s', got %v", name, expected, f)
+		}
+	}
+
+	// Flags replace the default of slices rather than appending to it.
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, ""), []string{"--tags", "c", "--server.port", "9090"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	if !reflect.DeepEqual(config.Tags, []string{"c"}) || config.Server.Port != 9090 {
+		t.Errorf("Expected tags [c] and port 9090, got %v and %d", config.Tags, config.Server.Port)
+	}
+
+	type InvalidDefaultConfig struct {
+		Port int `name:"port" description:"Server port" default:"eighty"`
+	}
+	_, err = New(&InvalidDefaultConfig{}, "")
+	if err == nil || !strings.Contains(err.Error(), `invalid default "eighty" for field Port`) {
+		t.Errorf("Expected invalid default error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationRange(t *testing.T) {
+	type RangeConfig struct {
+		Port    int     `name:"port" description:"Server port" min:"1" max:"65535"`
+		Workers uint8   `name:"workers" description:"Workers" max:"16"`
+		Offset  int64   `name:"offset" description:"Offset" min:"-10"`
+		Ratio   float32 `name:"ratio" description:"Ratio" min:"0" max:"1"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:       "InRange",
+			ConfigData: "port: 8080\nworkers: 16\noffset: -10\nratio: 0.5\n",
+		},
+		{
+			Name:        "BelowMin",
+			ConfigData:  "port: 0\n",
+			ExpectError: "flag port of field Port must be at least 1, got 0",
+		},
+		{
+			Name:        "AboveMaxFromFlag",
+			ConfigData:  "port: 8080\n",
+			CmdArgs:     []string{"--port", "70000"},
+			ExpectError: "flag port of field Port must be at most 65535, got 70000",
+		},
+		{
+			Name:        "UnsignedAboveMax",
+			ConfigData:  "port: 8080\nworkers: 17\n",
+			ExpectError: "flag workers of field Workers must be at most 16, got 17",
+		},
+		{
+			Name:        "SignedBelowMin",
+			ConfigData:  "port: 8080\noffset: -11\n",
+			ExpectError: "flag offset of field Offset must be at least -10, got -11",
+		},
+		{
+			Name:        "FloatAboveMax",
+			ConfigData:  "port: 8080\nratio: 1.5\n",
+			ExpectError: "flag ratio of field Ratio must be at most 1, got 1.5",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&RangeConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+
+	type InvalidRangeConfig struct {
+		Name string `name:"name" description:"Name" min:"1"`
+	}
+	manager, err := New(&InvalidRangeConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	err = parseWithArgs(t, manager, createTempConfigFile(t, ""), nil)
+	if err == nil || err.Error() != "invalid min tag of field Name: string fields have no range" {
+		t.Errorf("Expected invalid tag error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationChoices(t *testing.T) {
+	type ChoicesConfig struct {
+		Level  string `name:"level" description:"Log level" choices:"debug,info,warn,error"`
+		Format string `name:"format" choices:"json, text"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:       "Valid",
+			ConfigData: "level: warn\nformat: text\n",
+		},
+		{
+			Name:       "Empty",
+			ConfigData: "level: info\n",
+		},
+		{
+			Name:        "InvalidFromFile",
+			ConfigData:  "level: trace\n",
+			ExpectError: `flag level of field Level must be one of debug, info, warn, error, got "trace"`,
+		},
+		{
+			Name:        "InvalidFromFlag",
+			ConfigData:  "level: info\n",
+			CmdArgs:     []string{"--format", "xml"},
+			ExpectError: `flag format of field Format must be one of json, text, got "xml"`,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&ChoicesConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+
+	manager, err := New(&ChoicesConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	for name, expected := range map[string]string{
+		"level":  "Log level (one of: debug, info, warn, error)",
+		"format": "(one of: json, text)",
+	} {
+		if usage := manager.FlagSet().Lookup(name).Usage; usage != expected {
+			t.Errorf("Expected usage of %s to be '%s', got '%s'", name, expected, usage)
+		}
+	}
+}
+
+func TestParseConfigurationValidatePaths(t *testing.T) {
+	type PathConfig struct {
+		Cert    string `name:"cert" description:"Certificate file" validate:"file"`
+		DataDir string `name:"data-dir" description:"Data directory" validate:"dir"`
+	}
+
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	if err := os.WriteFile(cert, []byte("cert"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	for _, test := range []struct {
+		Name        string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name: "Unset",
+		},
+		{
+			Name:    "Existing",
+			CmdArgs: []string{"--cert", cert, "--data-dir", dir},
+		},
+		{
+			Name:        "MissingFile",
+			CmdArgs:     []string{"--cert", filepath.Join(dir, "missing.pem")},
+			ExpectError: "flag cert of field Cert must be a readable file",
+		},
+		{
+			Name:        "DirectoryForFile",
+			CmdArgs:     []string{"--cert", dir},
+			ExpectError: "flag cert of field Cert must be a file, got " + dir,
+		},
+		{
+			Name:        "FileForDirectory",
+			CmdArgs:     []string{"--data-dir", cert},
+			ExpectError: "flag data-dir of field DataDir must be a dir, got " + cert,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&PathConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, ""), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), test.ExpectError) {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+}
+
+type testProtocol string
+
+func TestParseConfigurationEnumSlices(t *testing.T) {
+	type EnumConfig struct {
+		Protocols []testProtocol `name:"protocols" description:"Protocols" enum:"http,https,grpc"`
+		Levels    []string       `name:"levels" description:"Levels" enum:"debug,info"`
+	}
+
+	for _, test := range []struct {
+		Name              string
+		ConfigData        string
+		CmdArgs           []string
+		ExpectedProtocols []testProtocol
+		ExpectError       string
+	}{
+		{
+			Name:              "Valid",
+			ConfigData:        "protocols: [http, grpc]\nlevels: [info]\n",
+			ExpectedProtocols: []testProtocol{"http", "grpc"},
+		},
+		{
+			Name:              "ValidFromFlag",
+			ConfigData:        "protocols: [http]\n",
+			CmdArgs:           []string{"--protocols", "https,grpc"},
+			ExpectedProtocols: []testProtocol{"https", "grpc"},
+		},
+		{
+			Name:       "Empty",
+			ConfigData: "protocols: []\n",
+		},
+		{
+			Name:        "InvalidFromFile",
+			ConfigData:  "protocols: [http, ftp]\n",
+			ExpectError: `elements of flag protocols of field Protocols must be one of http, https, grpc, got "ftp" at index 1`,
+		},
+		{
+			Name:        "InvalidFromFlag",
+			CmdArgs:     []string{"--levels", "info,trace"},
+			ExpectError: `elements of flag levels of field Levels must be one of debug, info, got "trace" at index 1`,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &EnumConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if len(config.Protocols) != len(test.ExpectedProtocols) || (len(test.ExpectedProtocols) > 0 && !reflect.DeepEqual(config.Protocols, test.ExpectedProtocols)) {
+				t.Errorf("Expected protocols %v, got %v", test.ExpectedProtocols, config.Protocols)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationWithWarnings(t *testing.T) {
+	type DeprecatedConfig struct {
+		Host    string `name:"host" description:"Server host"`
+		Address string `name:"address" description:"Server address" deprecated:"host"`
+	}
+
+	var handled []string
+	config := &DeprecatedConfig{}
+	manager, err := New(config, "", WithWarningHandler(func(msg string) {
+		handled = append(handled, msg)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().AddFlagSet(manager.FlagSet())
+	configPath := createTempConfigFile(t, `address: "localhost"`)
+	if err := cmd.ParseFlags([]string{"--config", configPath}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	warnings, err := manager.ParseConfigurationWithWarnings(cmd)
+	if err != nil {
+		t.Fatalf("ParseConfigurationWithWarnings failed: %v", err)
+	}
+	expected := []string{`config key "address" is deprecated, use "host" instead`}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Expected warnings %v, got %v", expected, warnings)
+	}
+	if len(handled) != 0 {
+		t.Errorf("Expected no warnings passed to the handler, got %v", handled)
+	}
+	if config.Address != "localhost" {
+		t.Errorf("Expected address 'localhost', got '%s'", config.Address)
+	}
+}
+
+func TestParseConfigurationBoolForms(t *testing.T) {
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		Expected    bool
+		ExpectError bool
+	}{
+		{Name: "One", ConfigData: "debug: 1", Expected: true},
+		{Name: "Zero", ConfigData: "debug: 0", Expected: false},
+		{Name: "QuotedOne", ConfigData: `debug: "1"`, Expected: true},
+		{Name: "QuotedTrue", ConfigData: `debug: "true"`, Expected: true},
+		{Name: "Bool", ConfigData: "debug: true", Expected: true},
+		{Name: "Invalid", ConfigData: "debug: 2", ExpectError: true},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &SimpleConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), nil)
+			if test.ExpectError {
+				if err == nil {
+					t.Error("Expected error for invalid bool")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if config.Debug != test.Expected {
+				t.Errorf("Expected debug %v, got %v", test.Expected, config.Debug)
+			}
+		})
+	}
+}
+
+func TestWithIndexedKeys(t *testing.T) {
+	type IndexedServer struct {
+		Ports []int `name:"ports" description:"Ports"`
+	}
+	type IndexedConfig struct {
+		Tags   []string      `name:"tags" description:"Tags"`
+		Server IndexedServer `name:"server"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		Expected    IndexedConfig
+		ExpectError string
+	}{
+		{
+			Name: "CollapsesIndexedKeys",
+			ConfigData: `
+tags.1: "second"
+tags.0: "first"
+server:
+  ports.0: 8080
+`,
+			Expected: IndexedConfig{
+				Tags:   []string{"first", "second"},
+				Server: IndexedServer{Ports: []int{8080}},
+			},
+		},
+		{
+			Name: "ListAndIndexedKeysErrors",
+			ConfigData: `
+tags: ["first"]
+tags.1: "second"
+`,
+			ExpectError: "could not parse config file: key tags is set both as a list and by index",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &IndexedConfig{}
+			manager, err := New(config, "", WithIndexedKeys())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = manager.LoadFile(createTempConfigFile(t, test.ConfigData))
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationMasksSecrets(t *testing.T) {
+	type SecretConfig struct {
+		Name     string `name:"name" description:"App name"`
+		Pin      int    `name:"pin" description:"PIN" secret:"true"`
+		Password string `name:"password" description:"Password" secret:"true"`
+	}
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Parser     func(string) error
+		Secret     string
+	}{
+		{
+			Name:       "ConfigFileError",
+			ConfigData: `pin: "hunter2"`,
+			Secret:     "hunter2",
+		},
+		{
+			Name:       "SetFlagError",
+			ConfigData: `name: "test"`,
+			CmdArgs:    []string{"--set", "pin=hunter3"},
+			Secret:     "hunter3",
+		},
+		{
+			Name:       "ParserError",
+			ConfigData: `password: "hunter4"`,
+			Parser: func(s string) error {
+				return fmt.Errorf("password %s is too short", s)
+			},
+			Secret: "hunter4",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &SecretConfig{}
+			manager, err := New(config, "", WithSetFlag())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if test.Parser != nil {
+				manager.RegisterParser("password", test.Parser)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if strings.Contains(err.Error(), test.Secret) {
+				t.Errorf("Expected secret to be masked, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), "******") {
+				t.Errorf("Expected masked value in error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationInterfaceFields(t *testing.T) {
+	type ConfigWithInterfaces struct {
+		Mode    any `name:"mode" description:"Mode"`
+		Retries any `name:"retries" description:"Retries"`
+		Verbose any `name:"verbose" description:"Verbose"`
+	}
+
+	config := &ConfigWithInterfaces{Mode: "fast", Retries: 3, Verbose: false}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	for name, expected := range map[string]struct {
+		Type     string
+		DefValue string
+	}{
+		"mode":    {Type: "string", DefValue: "fast"},
+		"retries": {Type: "int", DefValue: "3"},
+		"verbose": {Type: "bool", DefValue: "false"},
+	} {
+		f := manager.FlagSet().Lookup(name)
+		if f == nil {
+			t.Errorf("Expected flag %s", name)
+			continue
+		}
+		if f.Value.Type() != expected.Type || f.DefValue != expected.DefValue {
+			t.Errorf("Expected flag %s of type %s with default '%s', got %s with '%s'",
+				name, expected.Type, expected.DefValue, f.Value.Type(), f.DefValue)
+		}
+	}
+
+	configPath := createTempConfigFile(t, "mode: \"safe\"\nretries: 5\n")
+	if err := parseWithArgs(t, manager, configPath, []string{"--retries", "7", "--verbose"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	expected := ConfigWithInterfaces{Mode: "safe", Retries: 7, Verbose: true}
+	if !reflect.DeepEqual(*config, expected) {
+		t.Errorf("Expected config %+v, got %+v", expected, *config)
+	}
+}
+
+func TestParseConfigurationInterfaceFieldsFromFile(t *testing.T) {
+	type ConfigWithInterfaces struct {
+		Timeout any `name:"timeout" description:"Timeout"`
+		Port    any `name:"port" description:"Port"`
+	}
+
+	config := &ConfigWithInterfaces{Timeout: 5 * time.Second, Port: uint16(80)}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, "timeout: 10s\nport: 8080\n"), nil); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	expected := ConfigWithInterfaces{Timeout: 10 * time.Second, Port: uint16(8080)}
+	if !reflect.DeepEqual(*config, expected) {
+		t.Errorf("Expected config %#v, got %#v", expected, *config)
+	}
+
+	err = parseWithArgs(t, manager, createTempConfigFile(t, "port: 70000\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), "could not parse config file: key port") {
+		t.Errorf("Expected an error for a port out of range, got: %v", err)
+	}
+}
+
+func TestProcessStructNilInterface(t *testing.T) {
+	type ConfigWithInterface struct {
+		Value any `name:"value" description:"Value"`
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	err := processStruct("name", nil, flags, reflect.ValueOf(&ConfigWithInterface{}).Elem(), "")
+	if err == nil || !strings.Contains(err.Error(), "set a concrete default value or use a concrete type") {
+		t.Errorf("Expected guidance to use a concrete type, got: %v", err)
+	}
+
+	err = processStruct("name", nil, flags, reflect.ValueOf(&ConfigWithInterface{Value: []string{"a"}}).Elem(), "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported dynamic type []string") {
+		t.Errorf("Expected unsupported dynamic type error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationPointerFields(t *testing.T) {
+	type PointerConfig struct {
+		Name    *string        `name:"name" description:"Name"`
+		Port    *int           `name:"port" description:"Port"`
+		Debug   *bool          `name:"debug" description:"Debug mode"`
+		Timeout *time.Duration `name:"timeout" description:"Timeout"`
+	}
+	name := func(s string) *string { return &s }
+	port := func(i int) *int { return &i }
+	debug := func(b bool) *bool { return &b }
+	timeout := func(d time.Duration) *time.Duration { return &d }
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Expected   PointerConfig
+	}{
+		{
+			Name:     "Unset",
+			Expected: PointerConfig{},
+		},
+		{
+			Name:       "FromFile",
+			ConfigData: "name: app\nport: 0\ntimeout: 30s\n",
+			Expected:   PointerConfig{Name: name("app"), Port: port(0), Timeout: timeout(30 * time.Second)},
+		},
+		{
+			Name:       "FromFlags",
+			ConfigData: "name: app\n",
+			CmdArgs:    []string{"--name", "", "--debug", "--timeout", "1m"},
+			Expected:   PointerConfig{Name: name(""), Debug: debug(true), Timeout: timeout(time.Minute)},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &PointerConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+
+	// Pointers that have a default keep it as the flag default and aren't written through.
+	defaultPort := 8080
+	config := &PointerConfig{Port: &defaultPort}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if f := manager.FlagSet().Lookup("port"); f.DefValue != "8080" || f.Value.Type() != "int" {
+		t.Errorf("Expected int flag with default 8080, got %s with %s", f.Value.Type(), f.DefValue)
+	}
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, ""), []string{"--port", "9090"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	if *config.Port != 9090 || defaultPort != 8080 {
+		t.Errorf("Expected port 9090 and default 8080, got %d and %d", *config.Port, defaultPort)
+	}
+
+	type UnsupportedPointerConfig struct {
+		Tags *[]string `name:"tags" description:"Tags"`
+	}
+	_, err = New(&UnsupportedPointerConfig{}, "")
+	if err == nil || !strings.Contains(err.Error(), "unsupported pointer type *[]string") {
+		t.Errorf("Expected unsupported pointer type error, got: %v", err)
+	}
+}
+
+func TestParseConfigurationPointerFieldChecks(t *testing.T) {
+	type PointerConfig struct {
+		Name    *string `name:"name" description:"Name"`
+		Port    *int    `name:"port" description:"Port" min:"1"`
+		Level   *string `name:"level" description:"Level" choices:"debug,info"`
+		Enabled *bool   `name:"enabled" description:"Enabled"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		Expected    string
+		ExpectError string
+	}{
+		{
+			Name:     "Unset",
+			Expected: "<nil> <nil> <nil> <nil>",
+		},
+		{
+			Name:       "FromFile",
+			ConfigData: "name: app\nport: 80\nlevel: info\nenabled: 1\n",
+			Expected:   "APP 80 info true",
+		},
+		{
+			Name:        "BelowMin",
+			ConfigData:  "port: 0\n",
+			ExpectError: "flag port of field Port must be at least 1, got 0",
+		},
+		{
+			Name:        "InvalidChoice",
+			ConfigData:  "level: trace\n",
+			ExpectError: `flag level of field Level must be one of debug, info, got "trace"`,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &PointerConfig{}
+			manager, err := New(config, "", WithInterpolation())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			manager.RegisterTransform("name", strings.ToUpper)
+			manager.RegisterParser("level", func(string) error { return nil })
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), nil)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			show := func(v any) string {
+				rv := reflect.ValueOf(v)
+				if rv.IsNil() {
+					return "<nil>"
+				}
+				return fmt.Sprint(rv.Elem().Interface())
+			}
+			got := strings.Join([]string{show(config.Name), show(config.Port), show(config.Level), show(config.Enabled)}, " ")
+			if got != test.Expected {
+				t.Errorf("Expected %s, got %s", test.Expected, got)
+			}
+		})
+	}
+
+	manager, err := New(&PointerConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	data, err := manager.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"type": "integer"`) || !strings.Contains(string(data), `"type": "boolean"`) {
+		t.Errorf("Expected pointer fields typed like their values, got %s", data)
+	}
+}
+
+func TestManagerParseArgs(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, "name: \"from-config\"\nport: 8080\ndebug: true\n")
+	if err := manager.ParseArgs([]string{"--config", configPath, "-p", "9090", "--rate", "0.5", "positional"}); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+
+	if config.Name != "from-config" {
+		t.Errorf("Expected name 'from-config', got '%s'", config.Name)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+	if !config.Debug {
+		t.Error("Expected debug to be true")
+	}
+	if config.Rate != 0.5 {
+		t.Errorf("Expected rate 0.5, got %f", config.Rate)
+	}
+
+	err = manager.ParseArgs([]string{"--config", configPath, "--port", "not-a-number"})
+	if err == nil || !strings.Contains(err.Error(), "could not parse args") {
+		t.Errorf("Expected args error, got: %v", err)
+	}
+}
+
+func TestWithPrecedence(t *testing.T) {
+	configData := "name: \"from-config\"\nport: 8080\n"
+	args := []string{"--name", "from-flag", "--debug"}
+
+	for _, test := range []struct {
+		Name            string
+		Order           []Source
+		ExpectedName    string
+		ExpectedPort    int
+		ExpectedDebug   bool
+		ExpectedSources map[string]string
+	}{
+		{
+			Name:            "Default",
+			ExpectedName:    "from-flag",
+			ExpectedPort:    8080,
+			ExpectedDebug:   true,
+			ExpectedSources: map[string]string{"name": "flag", "port": "file", "debug": "flag"},
+		},
+		{
+			Name:            "FileOverridesFlags",
+			Order:           []Source{SourceFlag, SourceFile},
+			ExpectedName:    "from-config",
+			ExpectedPort:    8080,
+			ExpectedDebug:   true,
+			ExpectedSources: map[string]string{"name": "file", "port": "file", "debug": "flag"},
+		},
+		{
+			Name:            "IgnoresFlags",
+			Order:           []Source{SourceFile},
+			ExpectedName:    "from-config",
+			ExpectedPort:    8080,
+			ExpectedSources: map[string]string{"name": "file", "port": "file", "debug": "default"},
+		},
+		{
+			Name:            "IgnoresFile",
+			Order:           []Source{SourceFlag, SourceEnv},
+			ExpectedName:    "from-flag",
+			ExpectedDebug:   true,
+			ExpectedSources: map[string]string{"name": "flag", "port": "default", "debug": "flag"},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &SimpleConfig{}
+			opts := []Option{WithSourceTracking()}
+			if test.Order != nil {
+				opts = append(opts, WithPrecedence(test.Order...))
+			}
+			manager, err := New(config, "", opts...)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), args); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if config.Name != test.ExpectedName || config.Port != test.ExpectedPort || config.Debug != test.ExpectedDebug {
+				t.Errorf("Expected name '%s', port %d and debug %t, got '%s', %d and %t",
+					test.ExpectedName, test.ExpectedPort, test.ExpectedDebug, config.Name, config.Port, config.Debug)
+			}
+			for name, expected := range test.ExpectedSources {
+				if source := manager.SourceOf(name); source != expected {
+					t.Errorf("Expected source of %s to be %s, got %s", name, expected, source)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPrecedenceIgnoresDerivedFlags(t *testing.T) {
+	type Endpoint struct {
+		Host string `name:"host" description:"Host"`
+	}
+	type DerivedConfig struct {
+		Cache     bool       `name:"cache" description:"Enable the cache" negatable:"true"`
+		Endpoints []Endpoint `name:"endpoints" description:"Endpoints"`
+		Tags      []string   `name:"tags" description:"Tags"`
+	}
+
+	config := &DerivedConfig{Cache: true, Endpoints: []Endpoint{{Host: "a.example.com"}}}
+	manager, err := New(config, "", WithPrecedence(SourceFile))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	args := []string{"--no-cache", "--endpoints.0.host", "b.example.com", "--tags", "a"}
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, ""), args); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	if !config.Cache || !reflect.DeepEqual(config.Endpoints, []Endpoint{{Host: "a.example.com"}}) {
+		t.Errorf("Expected the defaults, got cache %t and endpoints %+v", config.Cache, config.Endpoints)
+	}
+	if config.Tags != nil {
+		t.Errorf("Expected the nil default of tags, got %#v", config.Tags)
+	}
+	for _, name := range []string{"no-cache", "endpoints.0.host"} {
+		if manager.FlagSet().Lookup(name).Changed {
+			t.Errorf("Expected flag %s to be reset", name)
+		}
+	}
+}
+
+func TestWithPrecedenceEnvOverridesFlags(t *testing.T) {
+	t.Setenv("TEST_PORT", "9090")
+	config := &SimpleConfig{}
+	manager, err := New(config, "", WithSourceTracking(), WithPrecedence(SourceFlag, SourceEnv, SourceFile))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.BindEnv("port", "TEST_PORT"); err != nil {
+		t.Fatalf("BindEnv failed: %v", err)
+	}
+
+	args := []string{"--port", "7070", "--name", "from-flag"}
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, "debug: true\n"), args); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+	expected := map[string]string{"port": "env", "name": "flag", "debug": "file", "timeout": "default"}
+	for name, source := range expected {
+		if got := manager.SourceOf(name); got != source {
+			t.Errorf("Expected source of %s to be %s, got %s", name, source, got)
+		}
+	}
+	for _, resolution := range manager.ResolutionReport() {
+		if source, ok := expected[resolution.Flag]; ok && resolution.Source != source {
+			t.Errorf("Expected resolution of %s from %s, got %s", resolution.Flag, source, resolution.Source)
+		}
+	}
+}
+
+func TestManagerBindEnv(t *testing.T) {
+	for _, test := range []struct {
+		Name           string
+		CmdArgs        []string
+		Env            map[string]string
+		ExpectedPort   int
+		ExpectedSource string
+	}{
+		{
+			Name:           "Unset",
+			ExpectedPort:   8080,
+			ExpectedSource: "file",
+		},
+		{
+			Name:           "EnvOverridesFile",
+			Env:            map[string]string{"TEST_PORT": "9090"},
+			ExpectedPort:   9090,
+			ExpectedSource: "env",
+		},
+		{
+			Name:           "FlagOverridesEnv",
+			CmdArgs:        []string{"--port", "7070"},
+			Env:            map[string]string{"TEST_PORT": "9090"},
+			ExpectedPort:   7070,
+			ExpectedSource: "flag",
+		},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			for key, value := range test.Env {
+				t.Setenv(key, value)
+			}
+			config := &SimpleConfig{}
+			manager, err := New(config, "", WithSourceTracking())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if err := manager.BindEnv("port", "TEST_PORT"); err != nil {
+				t.Fatalf("BindEnv failed: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, "port: 8080\n"), test.CmdArgs)
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if config.Port != test.ExpectedPort {
+				t.Errorf("Expected port %d, got %d", test.ExpectedPort, config.Port)
+			}
+			if source := manager.SourceOf("port"); source != test.ExpectedSource {
+				t.Errorf("Expected source '%s', got '%s'", test.ExpectedSource, source)
+			}
+		})
+	}
+
+	t.Run("Slice", func(t *testing.T) {
+		t.Setenv("TEST_TAGS", "a,b")
+		config := &ComplexConfig{}
+		manager, err := New(config, "")
+		if err != nil {
+			t.Fatalf("Failed to create manager: %v", err)
+		}
+		if err := manager.BindEnv("tags", "TEST_TAGS"); err != nil {
+			t.Fatalf("BindEnv failed: %v", err)
+		}
+		if err := parseWithArgs(t, manager, createTempConfigFile(t, "tags: [c]\n"), nil); err != nil {
+			t.Fatalf("ParseConfiguration failed: %v", err)
+		}
+		if !reflect.DeepEqual(config.Tags, []string{"a", "b"}) {
+			t.Errorf("Expected tags [a b], got %v", config.Tags)
+		}
+	})
+
+	manager, err := New(&SimpleConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.BindEnv("unknown", "TEST_UNKNOWN"); err == nil || err.Error() != "could not bind TEST_UNKNOWN: unknown flag unknown" {
+		t.Errorf("Expected unknown flag error, got: %v", err)
+	}
+}
+
+func TestManagerApply(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.AddFlagSet(manager.FlagSet())
+	configPath := createTempConfigFile(t, "name: \"from-config\"\nport: 8080\n")
+	if err := fs.Parse([]string{"--config", configPath, "--port", "9090"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := manager.Apply(fs); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if config.Name != "from-config" {
+		t.Errorf("Expected name 'from-config', got '%s'", config.Name)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+}
+
+func TestWithUnknownFlags(t *testing.T) {
+	configPath := createTempConfigFile(t, "name: \"from-config\"\n")
+	args := []string{"--config", configPath, "--unknown", "value", "--port", "9090"}
+
+	config := &SimpleConfig{}
+	manager, err := New(config, "", WithUnknownFlags(true))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.ParseArgs(args); err != nil {
+		t.Fatalf("ParseArgs failed: %v", err)
+	}
+	if config.Name != "from-config" || config.Port != 9090 {
+		t.Errorf("Expected name 'from-config' and port 9090, got '%s' and %d", config.Name, config.Port)
+	}
+
+	strict, err := New(&SimpleConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	err = strict.ParseArgs(args)
+	if err == nil || !strings.Contains(err.Error(), "unknown flag: --unknown") {
+		t.Errorf("Expected unknown flag error, got: %v", err)
+	}
+}
+
+// testMode is an enum that implements pflag.Value.
+type testMode int
+
+const (
+	testModeFast testMode = iota
+	testModeSafe
+)
+
+var testModeNames = []string{"fast", "safe"}
+
+func (m *testMode) String() string {
+	return testModeNames[*m]
+}
+
+func (m *testMode) Set(s string) error {
+	for i, name := range testModeNames {
+		if name == s {
+			*m = testMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(testModeNames, ", "))
+}
+
+func (m *testMode) Type() string {
+	return "mode"
+}
+
+func TestParseConfigurationValueFields(t *testing.T) {
+	type ModeServer struct {
+		Mode testMode `name:"mode" description:"Server mode"`
+	}
+	type ModeConfig struct {
+		Mode   testMode   `name:"mode" short:"m" description:"Mode"`
+		Server ModeServer `name:"server"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		Expected    ModeConfig
+		ExpectError string
+	}{
+		{
+			Name:       "FromConfigFile",
+			ConfigData: "mode: safe\nserver:\n  mode: safe\n",
+			Expected:   ModeConfig{Mode: testModeSafe, Server: ModeServer{Mode: testModeSafe}},
+		},
+		{
+			Name:       "FromFlag",
+			ConfigData: "mode: safe\n",
+			CmdArgs:    []string{"-m", "fast", "--server.mode", "safe"},
+			Expected:   ModeConfig{Mode: testModeFast, Server: ModeServer{Mode: testModeSafe}},
+		},
+		{
+			Name:        "InvalidConfigValue",
+			ConfigData:  "server:\n  mode: slow\n",
+			ExpectError: "could not parse config file: key server.mode: must be one of fast, safe",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ModeConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			if f := manager.FlagSet().Lookup("mode"); f == nil || f.Value.Type() != "mode" || f.DefValue != "fast" {
+				t.Fatalf("Expected mode flag with default 'fast', got %v", f)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
+
+func TestManagerResetFlag(t *testing.T) {
+	config := &ComplexConfig{
+		Server: ServerConfig{Host: "localhost", Port: 8080},
+		Tags:   []string{"a"},
+	}
+	manager, err := New(config, "", WithSourceTracking())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, "tags: [\"b\", \"c\"]\n")
+	args := []string{"--server.host", "example.com", "--server.port", "9090"}
+	if err := parseWithArgs(t, manager, configPath, args); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	for _, name := range []string{"server.host", "tags"} {
+		if err := manager.ResetFlag(name); err != nil {
+			t.Fatalf("ResetFlag %s failed: %v", name, err)
+		}
+	}
+
+	if config.Server.Host != "localhost" {
+		t.Errorf("Expected host 'localhost', got '%s'", config.Server.Host)
+	}
+	if !reflect.DeepEqual(config.Tags, []string{"a"}) {
+		t.Errorf("Expected tags [a], got %v", config.Tags)
+	}
+	if config.Server.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Server.Port)
+	}
+	if f := manager.FlagSet().Lookup("server.host"); f.Changed || f.Value.String() != "localhost" {
+		t.Errorf("Expected unchanged flag with value 'localhost', got %v '%s'", f.Changed, f.Value.String())
+	}
+	if source := manager.SourceOf("server.host"); source != "default" {
+		t.Errorf("Expected source 'default', got '%s'", source)
+	}
+
+	// Slice and map flags set after a reset replace the default instead of appending to it.
+	for name, values := range map[string][]string{"tags": {"x", "y"}, "metadata": {"k=x", "l=y"}} {
+		if err := manager.FlagSet().Set(name, values[0]); err != nil {
+			t.Fatalf("Set %s failed: %v", name, err)
+		}
+		if err := manager.ResetFlag(name); err != nil {
+			t.Fatalf("ResetFlag %s failed: %v", name, err)
+		}
+		if err := manager.FlagSet().Set(name, values[1]); err != nil {
+			t.Fatalf("Set %s failed: %v", name, err)
+		}
+	}
+	if !reflect.DeepEqual(config.Tags, []string{"y"}) {
+		t.Errorf("Expected tags [y], got %v", config.Tags)
+	}
+	if !reflect.DeepEqual(config.Metadata, map[string]string{"l": "y"}) {
+		t.Errorf("Expected metadata map[l:y], got %v", config.Metadata)
+	}
+
+	if err := manager.ResetFlag("missing"); err == nil || !strings.Contains(err.Error(), "flag not found") {
+		t.Errorf("Expected flag not found error, got: %v", err)
+	}
+}
+
+func TestManagerMutuallyExclusive(t *testing.T) {
+	type TokenConfig struct {
+		Token     string `name:"token" description:"Token"`
+		TokenFile string `name:"token-file" description:"Token file"`
+		Name      string `name:"name" description:"Name"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:       "OneSet",
+			ConfigData: `token: "secret"`,
+			CmdArgs:    []string{"--name", "test"},
+		},
+		{
+			Name:        "BothSet",
+			ConfigData:  `token: "secret"`,
+			CmdArgs:     []string{"--token-file", "/run/token"},
+			ExpectError: "flags token, token-file are mutually exclusive",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&TokenConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			manager.MutuallyExclusive("token", "token-file")
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestManagerLoadFileGzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte("name: \"compressed\"\nport: 9090\n")); err != nil {
+		t.Fatalf("Failed to compress config: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to compress config: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yml.gz")
+	if err := os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create temp config file: %v", err)
+	}
+
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := parseWithArgs(t, manager, configPath, nil); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	if config.Name != "compressed" {
+		t.Errorf("Expected name 'compressed', got '%s'", config.Name)
+	}
+	if config.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", config.Port)
+	}
+}
+
+func TestManagerFlags(t *testing.T) {
+	type InfoServer struct {
+		Host string `name:"host" short:"H" description:"Server host" hidden:"true" required:"true"`
+		Port int    `name:"port" description:"Server port"`
+		Bind string `name:"bind" description:"Bind address" deprecated:"server.host"`
+	}
+	type InfoConfig struct {
+		Server InfoServer `name:"server"`
+	}
+
+	manager, err := New(&InfoConfig{Server: InfoServer{Host: "localhost", Port: 8080}}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().AddFlagSet(manager.FlagSet())
+	if err := cmd.MarkFlagRequired("server.port"); err != nil {
+		t.Fatalf("Failed to mark flag required: %v", err)
+	}
+
+	expected := []FlagInfo{
+		{
+			Name:        "server.bind",
+			Type:        "string",
+			Description: "Bind address",
+			// pflag hides deprecated flags.
+			Hidden:     true,
+			Deprecated: "use --server.host instead",
+		},
+		{
+			Name:        "server.host",
+			Shorthand:   "H",
+			Type:        "string",
+			Default:     "localhost",
+			Description: "Server host",
+			Hidden:      true,
+			// The required tag counts without ApplyRequired.
+			Required: true,
+		},
+		{
+			Name:        "server.port",
+			Type:        "int",
+			Default:     "8080",
+			Description: "Server port",
+			Required:    true,
+		},
+	}
+	if flags := manager.Flags(); !reflect.DeepEqual(flags, expected) {
+		t.Errorf("Expected flags %+v, got %+v", expected, flags)
+	}
+}
+
+func TestManagerHelpMarkdown(t *testing.T) {
+	type HelpServer struct {
+		Host   string `name:"host" short:"H" description:"Server host"`
+		Port   int    `name:"port" description:"Server port"`
+		Secret string `name:"secret" description:"Hidden value" hidden:"true"`
+	}
+	type HelpConfig struct {
+		Name   string     `name:"name" description:"Name | alias"`
+		Server HelpServer `name:"server"`
+	}
+
+	manager, err := New(&HelpConfig{Server: HelpServer{Host: "localhost", Port: 8080}}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	expected := "| Flag | Type | Default | Description |\n" +
+		"| --- | --- | --- | --- |\n" +
+		"| `--name` | string |  | Name \\| alias |\n" +
+		"| `--server.host`, `-H` | string | `localhost` | Server host |\n" +
+		"| `--server.port` | int | `8080` | Server port |\n"
+	if markdown := manager.HelpMarkdown(); markdown != expected {
+		t.Errorf("Expected markdown:\n%s\ngot:\n%s", expected, markdown)
+	}
+}
+
+func TestManagerApplyRequired(t *testing.T) {
+	type RequiredServer struct {
+		Host string `name:"host" description:"Server host" required:"true"`
+		Port int    `name:"port" description:"Server port"`
+	}
+	type RequiredConfig struct {
+		Server RequiredServer `name:"server"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:        "MissingRequiredFlag",
+			CmdArgs:     []string{"--server.port", "8080"},
+			ExpectError: `required flag(s) "server.host" not set`,
+		},
+		{
+			Name:    "RequiredFlagSet",
+			CmdArgs: []string{"--server.host", "localhost"},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&RequiredConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			cmd := &cobra.Command{
+				Use:           "test",
+				SilenceErrors: true,
+				SilenceUsage:  true,
+				RunE:          func(cmd *cobra.Command, args []string) error { return nil },
+			}
+			cmd.Flags().AddFlagSet(manager.FlagSet())
+			if err := manager.ApplyRequired(cmd); err != nil {
+				t.Fatalf("ApplyRequired failed: %v", err)
+			}
+
+			cmd.SetArgs(test.CmdArgs)
+			err = cmd.Execute()
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+		})
+	}
+
+	manager, err := New(&RequiredConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := manager.ApplyRequired(&cobra.Command{Use: "test"}); err == nil {
+		t.Error("Expected error for a command without the flags")
+	}
+
+	type SectionConfig struct {
+		Server RequiredServer `name:"server" required:"true"`
+	}
+	manager, err = New(&SectionConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().AddFlagSet(manager.FlagSet())
+	if err := manager.ApplyRequired(cmd); err != nil {
+		t.Fatalf("ApplyRequired with a required section failed: %v", err)
+	}
+	if f := cmd.Flags().Lookup("server.host"); len(f.Annotations[cobra.BashCompOneRequiredFlag]) == 0 {
+		t.Error("Expected the required field of the section to be marked required")
+	}
+}
+
+func TestParseConfigurationRequired(t *testing.T) {
+	type RequiredServer struct {
+		Host string `name:"host" description:"Server host" required:"true"`
+		Port int    `name:"port" description:"Server port" required:"true"`
+	}
+	type RequiredConfig struct {
+		Name   string         `name:"name" description:"Name" required:"true"`
+		Tags   []string       `name:"tags" description:"Tags" required:"true"`
+		Server RequiredServer `name:"server"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:        "AllMissing",
+			ExpectError: "required fields not set: name, tags, server.host, server.port",
+		},
+		{
+			Name:        "SomeMissing",
+			ConfigData:  "name: app\nserver:\n  host: localhost\n",
+			ExpectError: "required fields not set: tags, server.port",
+		},
+		{
+			Name:       "FromFileAndFlags",
+			ConfigData: "name: app\nserver:\n  host: localhost\n",
+			CmdArgs:    []string{"--tags", "a", "--server.port", "8080"},
+		},
+		{
+			Name:       "ZeroFromFlag",
+			ConfigData: "name: app\ntags: [a]\nserver:\n  host: localhost\n",
+			CmdArgs:    []string{"--server.port", "0"},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&RequiredConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationRequiredSection(t *testing.T) {
+	type DatabaseConfig struct {
+		Host string `name:"host" description:"Database host"`
+		Pool int    `name:"pool" description:"Pool size"`
+	}
+	type SectionConfig struct {
+		Name     string         `name:"name" description:"Name"`
+		Database DatabaseConfig `name:"database" required:"true"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+	}{
+		{
+			Name:       "Present",
+			ConfigData: "database:\n  pool: 0\n",
+		},
+		{
+			Name:        "Absent",
+			ConfigData:  "name: app\n",
+			ExpectError: "required sections missing from the config file: database",
+		},
+		{
+			Name:       "FromFlag",
+			ConfigData: "name: app\n",
+			CmdArgs:    []string{"--database.host", "localhost"},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&SectionConfig{Database: DatabaseConfig{Pool: 4}}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationBase64(t *testing.T) {
+	type EncodedConfig struct {
+		Password string `name:"password" description:"Password" encoding:"base64"`
+		Key      []byte `name:"key" description:"Key" encoding:"base64"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		Expected    EncodedConfig
+		ExpectError string
+	}{
+		{
+			Name:       "FromConfigFile",
+			ConfigData: "password: \"czNjcjN0IQ==\"\nkey: \"AAEC\"\n",
+			Expected:   EncodedConfig{Password: "s3cr3t!", Key: []byte{0, 1, 2}},
+		},
+		{
+			Name:       "FromFlags",
+			ConfigData: `password: "czNjcjN0IQ=="`,
+			CmdArgs:    []string{"--password", "b3ZlcnJpZGU=", "--key", "AwQ="},
+			Expected:   EncodedConfig{Password: "override", Key: []byte{3, 4}},
+		},
+		{
+			Name:        "InvalidString",
+			ConfigData:  `password: "not base64"`,
+			ExpectError: "could not parse config file: key password: invalid base64",
+		},
+		{
+			Name:        "InvalidBytes",
+			ConfigData:  `key: "not base64"`,
+			ExpectError: "could not parse config file: key key: invalid base64",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &EncodedConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationBase64Secret(t *testing.T) {
+	type SecretConfig struct {
+		Mode string `name:"mode" description:"Mode" encoding:"base64" secret:"true" choices:"a,b"`
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte("leaked-secret"))
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+	}{
+		{
+			Name:       "FromConfigFile",
+			ConfigData: "mode: " + encoded + "\n",
+		},
+		{
+			Name:    "FromFlag",
+			CmdArgs: []string{"--mode", encoded},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&SecretConfig{}, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if err == nil {
+				t.Fatal("Expected an error for a value that isn't a choice")
+			}
+			if strings.Contains(err.Error(), "leaked-secret") || strings.Contains(err.Error(), encoded) {
+				t.Errorf("Expected the secret to be masked, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseConfigurationBase64Resolve(t *testing.T) {
+	type EncodedConfig struct {
+		Greeting string  `name:"greeting" description:"Greeting" encoding:"base64"`
+		Message  *string `name:"message" description:"Message" encoding:"base64"`
+		Name     string  `name:"name" description:"Name"`
+	}
+
+	config := &EncodedConfig{}
+	manager, err := New(config, "", WithInterpolation())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	manager.RegisterTransform("greeting", strings.ToUpper)
+	var parsed []string
+	manager.RegisterParser("message", func(s string) error {
+		parsed = append(parsed, s)
+		return nil
+	})
+
+	configData := fmt.Sprintf("greeting: %s\nmessage: %s\nname: world\n",
+		base64.StdEncoding.EncodeToString([]byte("hello")),
+		base64.StdEncoding.EncodeToString([]byte("{greeting} {name}")))
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), nil); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	if config.Greeting != "HELLO" {
+		t.Errorf("Expected greeting 'HELLO', got %q", config.Greeting)
+	}
+	// Interpolation runs before the transforms.
+	if config.Message == nil || *config.Message != "hello world" {
+		t.Errorf("Expected message 'hello world', got %v", config.Message)
+	}
+	if !slices.Equal(parsed, []string{"hello world"}) {
+		t.Errorf("Expected the parser to get the decoded message, got %q", parsed)
+	}
+}
+
+func TestParseConfigurationBase64Twice(t *testing.T) {
+	type EncodedConfig struct {
+		Password string `name:"password" description:"Password" encoding:"base64" default:"czNjcjN0IQ=="`
+		Token    string `name:"token" description:"Token" encoding:"base64"`
+	}
+
+	config := &EncodedConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	t.Setenv("TEST_TOKEN", "dG9rZW4=")
+	if err := manager.BindEnv("token", "TEST_TOKEN"); err != nil {
+		t.Fatalf("BindEnv failed: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, "")
+	for i := 0; i < 2; i++ {
+		if err := parseWithArgs(t, manager, configPath, nil); err != nil {
+			t.Fatalf("ParseConfiguration %d failed: %v", i+1, err)
+		}
+		if config.Password != "s3cr3t!" || config.Token != "token" {
+			t.Errorf("Expected password 's3cr3t!' and token 'token', got '%s' and '%s'", config.Password, config.Token)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := manager.DumpConfig(&buf, "yaml"); err != nil {
+		t.Fatalf("DumpConfig failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "password: czNjcjN0IQ==") {
+		t.Errorf("Expected the dump to encode the password, got:\n%s", buf.String())
+	}
+}
+
+func TestParseConfigurationBytes(t *testing.T) {
+	type BytesConfig struct {
+		Hex    []byte `name:"hex" description:"Hex key"`
+		Base64 []byte `name:"base64" description:"Base64 key" encoding:"base64"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		Expected    BytesConfig
+		ExpectError string
+	}{
+		{
+			Name:       "FromConfigFile",
+			ConfigData: "hex: \"deadbeef\"\nbase64: \"3q2+7w==\"\n",
+			Expected:   BytesConfig{Hex: []byte{0xde, 0xad, 0xbe, 0xef}, Base64: []byte{0xde, 0xad, 0xbe, 0xef}},
+		},
+		{
+			Name:       "FromFlags",
+			ConfigData: `hex: "deadbeef"`,
+			CmdArgs:    []string{"--hex", "0102", "--base64", "AwQ="},
+			Expected:   BytesConfig{Hex: []byte{1, 2}, Base64: []byte{3, 4}},
+		},
+		{
+			Name:        "InvalidHex",
+			ConfigData:  `hex: "xyz"`,
+			ExpectError: "could not parse config file: key hex: invalid hex",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &BytesConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+
+			// The flag prints the value back in the same encoding.
+			flag := manager.flags.Lookup("hex")
+			if got := flag.Value.String(); got != strings.ToUpper(hex.EncodeToString(config.Hex)) {
+				t.Errorf("Expected hex flag value %X, got %s", config.Hex, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Difference is a field whose value differs between two configurations.
+type Difference struct {
+	// Flag is the flag name of the field.
+	Flag string
+	// Old is the value in the Manager's target.
+	Old string
+	// New is the value in the other configuration.
+	New string
+}
+
+// Diff compares the Manager's target with other, which must be a struct or pointer to a struct of the same type.
+// It returns the differing fields in the order they're declared.
+// The values of fields tagged secret:"true" or matching WithRedactKeys are masked on both sides.
+func (m *Manager) Diff(other any) ([]Difference, error) {
+	oldValue := reflect.ValueOf(m.target).Elem()
+	newValue := reflect.Indirect(reflect.ValueOf(other))
+	if !newValue.IsValid() {
+		return nil, fmt.Errorf("could not diff configuration: expected %s, got nil", oldValue.Type())
+	}
+	if newValue.Type() != oldValue.Type() {
+		return nil, fmt.Errorf("could not diff configuration: expected %s, got %s", oldValue.Type(), newValue.Type())
+	}
+	// Work on a copy, since walkFields only visits settable fields.
+	newCopy := reflect.New(newValue.Type()).Elem()
+	newCopy.Set(newValue)
+
+	oldFields, err := leafFields(m.nameTag, m.normalize, oldValue)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := leafFields(m.nameTag, m.normalize, newCopy)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []Difference
+	for i, f := range oldFields {
+		oldField, newField := f.value.Interface(), newFields[i].value.Interface()
+		if reflect.DeepEqual(oldField, newField) {
+			continue
+		}
+		d := Difference{
+			Flag: f.name,
+			Old:  fmt.Sprint(oldField),
+			New:  fmt.Sprint(newField),
+		}
+		if m.isSecret(f) {
+			d.Old, d.New = maskedValue, maskedValue
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManagerDiff(t *testing.T) {
+	type Database struct {
+		Host     string `name:"host" description:"Database host"`
+		Password string `name:"password" description:"Database password" secret:"true"`
+	}
+	type DiffConfig struct {
+		Name     string       `name:"name" description:"App name"`
+		Server   ServerConfig `name:"server"`
+		Database Database     `name:"database"`
+		Tags     []string     `name:"tags" description:"Tags"`
+	}
+
+	current := &DiffConfig{
+		Name:     "app",
+		Server:   ServerConfig{Host: "old.example.com", Port: 8080},
+		Database: Database{Host: "db", Password: "old-secret"},
+		Tags:     []string{"a"},
+	}
+	manager, err := New(current, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	next := *current
+	next.Server.Host = "new.example.com"
+	next.Server.Port = 9090
+	next.Database.Password = "new-secret"
+
+	diffs, err := manager.Diff(next)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	expected := []Difference{
+		{Flag: "server.host", Old: "old.example.com", New: "new.example.com"},
+		{Flag: "server.port", Old: "8080", New: "9090"},
+		{Flag: "database.password", Old: "******", New: "******"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expected differences %+v, got %+v", expected, diffs)
+	}
+
+	diffs, err = manager.Diff(&next)
+	if err != nil {
+		t.Fatalf("Diff with pointer failed: %v", err)
+	}
+	if len(diffs) != len(expected) {
+		t.Errorf("Expected %d differences with pointer, got %d", len(expected), len(diffs))
+	}
+
+	_, err = manager.Diff(&SimpleConfig{})
+	if err == nil || !strings.Contains(err.Error(), "could not diff configuration") {
+		t.Errorf("Expected type mismatch error, got: %v", err)
+	}
+	for _, other := range []any{nil, (*ComplexConfig)(nil), "config"} {
+		if _, err := manager.Diff(other); err == nil || !strings.Contains(err.Error(), "could not diff configuration") {
+			t.Errorf("Expected an error for %#v, got: %v", other, err)
+		}
+	}
+}
+
+func TestManagerDiffRedactKeys(t *testing.T) {
+	type RedactConfig struct {
+		Host     string `name:"host" description:"Host"`
+		APIToken string `name:"api-token" description:"API token"`
+	}
+
+	current := &RedactConfig{Host: "old", APIToken: "old-token"}
+	manager, err := New(current, "", WithRedactKeys("TOKEN"), WithSourceTracking())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	diffs, err := manager.Diff(RedactConfig{Host: "new", APIToken: "new-token"})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	expected := []Difference{
+		{Flag: "host", Old: "old", New: "new"},
+		{Flag: "api-token", Old: "******", New: "******"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("Expected differences %+v, got %+v", expected, diffs)
+	}
+
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, ""), []string{"--api-token", "flag-token"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+	expectedReport := []Resolution{
+		{Flag: "api-token", Source: "flag", Value: "******", Overridden: true},
+		{Flag: "host", Source: "default", Value: "old", Overridden: false},
+	}
+	if report := manager.ResolutionReport(); !reflect.DeepEqual(report, expectedReport) {
+		t.Errorf("Expected report %+v, got %+v", expectedReport, report)
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DumpConfig writes the current values of the target to w in format, "yaml" or "json", for example to show
+// which values won after ParseConfiguration. Keys are the names of the flags, nested like the flags,
+// durations and byte slices are written in their flag form and the values of secret fields are masked.
+// A marshaler set with WithMarshaler replaces the one of the format.
+func (m *Manager) DumpConfig(w io.Writer, format string) error {
+	values := m.dumpStruct(reflect.ValueOf(m.target).Elem(), "")
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case m.marshal != nil:
+		data, err = m.marshal(values)
+	case format == "yaml":
+		data, err = yaml.Marshal(values)
+	case format == "json":
+		data, err = json.MarshalIndent(values, "", "  ")
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("could not dump configuration: unsupported format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("could not dump configuration: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("could not dump configuration: %w", err)
+	}
+	return nil
+}
+
+// dumpStruct returns the values of the fields of the struct v, whose flags are under prefix, by name.
+func (m *Manager) dumpStruct(v reflect.Value, prefix string) map[string]any {
+	values := make(map[string]any)
+	// walkFields only returns the errors of its callback, and this one has none.
+	_ = walkFields(m.nameTag, m.normalize, v, prefix, nil, func(f field) error {
+		// Nested fields are added by their struct.
+		if len(f.path) != 1 {
+			return nil
+		}
+		values[strings.TrimPrefix(f.name, prefix+".")] = m.dumpValue(f)
+		return nil
+	})
+	return values
+}
+
+// dumpValue returns the value of a field to dump.
+func (m *Manager) dumpValue(f field) any {
+	switch {
+	case m.isSecret(f):
+		return maskedValue
+	case f.value.Kind() == reflect.Struct && f.value.Type() != timeType && !isValue(f.value):
+		return m.dumpStruct(f.value, f.name)
+	}
+	return fileValue(f)
+}
+
+// fileValue returns the value of a field that isn't a nested struct as it's written in the config file:
+// durations, byte slices, base64 encoded strings and fields that implement pflag.Value are written in their flag form,
+// and pointers as the value they point to.
+func fileValue(f field) any {
+	switch {
+	case f.value.Kind() == reflect.Pointer:
+		if f.value.IsNil() {
+			return nil
+		}
+		f.value = f.value.Elem()
+		return fileValue(f)
+	case isValue(f.value):
+		return f.value.Addr().Interface().(fmt.Stringer).String()
+	case f.value.Type() == durationType:
+		return time.Duration(f.value.Int()).String()
+	case f.value.Kind() == reflect.Slice && f.value.Type().Elem().Kind() == reflect.Uint8:
+		if f.structField.Tag.Get("encoding") == "base64" {
+			return base64.StdEncoding.EncodeToString(f.value.Bytes())
+		}
+		return hex.EncodeToString(f.value.Bytes())
+	case f.value.Kind() == reflect.String && f.structField.Tag.Get("encoding") == "base64":
+		return base64.StdEncoding.EncodeToString([]byte(f.value.String()))
+	}
+	return f.value.Interface()
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManagerDumpConfig(t *testing.T) {
+	type DumpServer struct {
+		Host    string        `name:"host" description:"Server host"`
+		Timeout time.Duration `name:"timeout" description:"Timeout"`
+	}
+	type DumpConfig struct {
+		BaseURL  string     `name:"base-url" yaml:"base_url" description:"Base URL"`
+		Server   DumpServer `name:"server"`
+		Tags     []string   `name:"tags" description:"Tags"`
+		Key      []byte     `name:"key" description:"Key"`
+		Password string     `name:"password" description:"Password" secret:"true"`
+	}
+
+	config := &DumpConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	configData := `
+base_url: "https://example.com"
+server:
+  host: "localhost"
+  timeout: "30s"
+key: "0102"
+password: "s3cr3t"
+`
+	if err := parseWithArgs(t, manager, createTempConfigFile(t, configData), []string{"--tags", "a,b"}); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	for _, test := range []struct {
+		Format   string
+		Expected string
+	}{
+		{
+			Format: "yaml",
+			Expected: `base-url: https://example.com
+key: "0102"
+password: '******'
+server:
+    host: localhost
+    timeout: 30s
+tags:
+    - a
+    - b
+`,
+		},
+		{
+			Format: "json",
+			Expected: `{
+  "base-url": "https://example.com",
+  "key": "0102",
+  "password": "******",
+  "server": {
+    "host": "localhost",
+    "timeout": "30s"
+  },
+  "tags": [
+    "a",
+    "b"
+  ]
+}
+`,
+		},
+	} {
+		test := test
+		t.Run(test.Format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := manager.DumpConfig(&buf, test.Format); err != nil {
+				t.Fatalf("DumpConfig failed: %v", err)
+			}
+			if buf.String() != test.Expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", test.Expected, buf.String())
+			}
+		})
+	}
+
+	err = manager.DumpConfig(&bytes.Buffer{}, "toml")
+	if err == nil || !strings.Contains(err.Error(), "unsupported format toml") {
+		t.Errorf("Expected unsupported format error, got: %v", err)
+	}
+}
+
+func TestWithMarshaler(t *testing.T) {
+	type MarshalConfig struct {
+		Name string `name:"name" description:"Name"`
+	}
+
+	var marshaled any
+	manager, err := New(&MarshalConfig{Name: "app"}, "", WithMarshaler(func(v any) ([]byte, error) {
+		marshaled = v
+		return []byte("custom output\n"), nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := manager.DumpConfig(&buf, "yaml"); err != nil {
+		t.Fatalf("DumpConfig failed: %v", err)
+	}
+	if buf.String() != "custom output\n" {
+		t.Errorf("Expected the custom marshaler's output, got %q", buf.String())
+	}
+	if expected := map[string]any{"name": "app"}; !reflect.DeepEqual(marshaled, expected) {
+		t.Errorf("Expected marshaled values %+v, got %+v", expected, marshaled)
+	}
+}
+
+func TestWithPrintConfigFlag(t *testing.T) {
+	type PrintConfig struct {
+		Name     string `name:"name" description:"Name"`
+		Port     int    `name:"port" description:"Port"`
+		Password string `name:"password" description:"Password" secret:"true"`
+	}
+	configData := "name: app\npassword: s3cr3t\n"
+
+	for _, test := range []struct {
+		Name     string
+		CmdArgs  []string
+		Expected string
+	}{
+		{
+			Name:     "NotSet",
+			CmdArgs:  []string{"--port", "8080"},
+			Expected: "",
+		},
+		{
+			Name:     "YAML",
+			CmdArgs:  []string{"--port", "8080", "--print-config"},
+			Expected: "name: app\npassword: '******'\nport: 8080\n",
+		},
+		{
+			Name:     "JSON",
+			CmdArgs:  []string{"--print-config=json"},
+			Expected: "{\n  \"name\": \"app\",\n  \"password\": \"******\",\n  \"port\": 0\n}\n",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			manager, err := New(&PrintConfig{}, "", WithPrintConfigFlag())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+			var buf bytes.Buffer
+			manager.stdout = &buf
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, configData), test.CmdArgs)
+			if test.Expected == "" {
+				if err != nil {
+					t.Fatalf("ParseConfiguration failed: %v", err)
+				}
+			} else if !errors.Is(err, ErrConfigPrinted) {
+				t.Fatalf("Expected ErrConfigPrinted, got: %v", err)
+			}
+			if buf.String() != test.Expected {
+				t.Errorf("Expected output:\n%s\ngot:\n%s", test.Expected, buf.String())
+			}
+		})
+	}
+
+	manager, err := New(&PrintConfig{}, "", WithPrintConfigFlag())
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if manager.UserFlagSet().Lookup("print-config") != nil {
+		t.Error("Expected --print-config to be left out of the user flags")
+	}
+}
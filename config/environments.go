@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyEnvironment merges the active environment of a config file over its top level keys.
+// The active and environments keys are removed from the document, so they don't need fields in the target.
+func (m *Manager) applyEnvironment(doc *yaml.Node) error {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+	active := removeKey(root, m.activeEnvironmentKey)
+	environments := removeKey(root, m.environmentsKey)
+	if active == nil || active.Value == "" {
+		return nil
+	}
+	var overlay *yaml.Node
+	if environments != nil {
+		overlay = lookupNode(environments, []string{active.Value})
+	}
+	if overlay == nil {
+		return fmt.Errorf("could not parse config file: environment %s not found in %s", active.Value, m.environmentsKey)
+	}
+	mergeNode(root, overlay)
+	return nil
+}
+
+// removeKey removes a key from a mapping node and returns its value, or nil if there isn't one.
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			value := node.Content[i+1]
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return value
+		}
+	}
+	return nil
+}
+
+// mergeNode merges overlay into base. Mappings are merged key by key and any other node replaces base.
+func mergeNode(base, overlay *yaml.Node) {
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		*base = *overlay
+		return
+	}
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if existing := lookupNode(base, []string{key.Value}); existing != nil {
+			mergeNode(existing, value)
+			continue
+		}
+		base.Content = append(base.Content, key, value)
+	}
+}
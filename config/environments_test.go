@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithActiveEnvironmentKey(t *testing.T) {
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		Expected    ComplexConfig
+		ExpectError string
+	}{
+		{
+			Name: "ActiveEnvironmentWins",
+			ConfigData: `
+active_environment: production
+basic:
+  name: "app"
+server:
+  host: "localhost"
+  port: 8080
+environments:
+  staging:
+    server:
+      host: "staging.example.com"
+  production:
+    basic:
+      version: "2.0.0"
+    server:
+      host: "example.com"
+    tags: ["prod"]
+`,
+			Expected: ComplexConfig{
+				Basic:  BasicInfo{Name: "app", Version: "2.0.0"},
+				Server: ServerConfig{Host: "example.com", Port: 8080},
+				Tags:   []string{"prod"},
+			},
+		},
+		{
+			Name: "NoActiveEnvironment",
+			ConfigData: `
+server:
+  host: "localhost"
+environments:
+  production:
+    server:
+      host: "example.com"
+`,
+			Expected: ComplexConfig{
+				Server: ServerConfig{Host: "localhost"},
+			},
+		},
+		{
+			Name: "UnknownEnvironmentErrors",
+			ConfigData: `
+active_environment: development
+environments:
+  production:
+    server:
+      host: "example.com"
+`,
+			ExpectError: "could not parse config file: environment development not found in environments",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ComplexConfig{}
+			manager, err := New(config, "", WithActiveEnvironmentKey("active_environment", "environments"))
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = manager.LoadFile(createTempConfigFile(t, test.ConfigData))
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
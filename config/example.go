@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateExample writes an example config file for the target to w, with every tagged field set to its
+// current value, which is its default before the configuration is parsed, and its description as a comment.
+// Nested structs are nested mappings, unless they're tagged flatten:"true".
+func (m *Manager) GenerateExample(w io.Writer) error {
+	node, err := m.exampleNode(reflect.ValueOf(m.target).Elem(), "")
+	if err != nil {
+		return fmt.Errorf("could not generate example config: %w", err)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return fmt.Errorf("could not generate example config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("could not generate example config: %w", err)
+	}
+	return nil
+}
+
+// exampleNode returns the mapping of the struct v, whose flags are under prefix, in the example config file.
+func (m *Manager) exampleNode(v reflect.Value, prefix string) (*yaml.Node, error) {
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	err := walkFields(m.nameTag, m.normalize, v, prefix, nil, func(f field) error {
+		// Nested fields are added by their struct.
+		if len(f.path) != 1 {
+			return nil
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.path[0]}
+		if f.value.Kind() == reflect.Struct && f.value.Type() != timeType && !isValue(f.value) {
+			nested, err := m.exampleNode(f.value, f.name)
+			if err != nil {
+				return err
+			}
+			if f.structField.Tag.Get("flatten") == "true" {
+				mapping.Content = append(mapping.Content, nested.Content...)
+			} else {
+				mapping.Content = append(mapping.Content, key, nested)
+			}
+			return nil
+		}
+
+		value := &yaml.Node{}
+		if err := value.Encode(fileValue(f)); err != nil {
+			return fmt.Errorf("field %s: %w", f.structField.Name, err)
+		}
+		description := f.structField.Tag.Get("description")
+		if fl := m.flags.Lookup(f.name); fl != nil && fl.Usage != "" {
+			description = fl.Usage
+		}
+		// Comments of block values go after the key, since the value starts on the next line.
+		if value.Kind == yaml.ScalarNode || len(value.Content) == 0 {
+			value.LineComment = description
+		} else {
+			key.LineComment = description
+		}
+		mapping.Content = append(mapping.Content, key, value)
+		return nil
+	})
+	return mapping, err
+}
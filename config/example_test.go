@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManagerGenerateExample(t *testing.T) {
+	type ExampleServer struct {
+		Host    string        `name:"host" description:"Server host"`
+		Port    int           `name:"port" description:"Server port"`
+		Timeout time.Duration `name:"timeout" description:"Request timeout"`
+	}
+	type ExampleConfig struct {
+		Name     string            `name:"name" description:"App name"`
+		Server   ExampleServer     `name:"server"`
+		Tags     []string          `name:"tags" description:"Tags"`
+		Metadata map[string]string `name:"metadata" description:"Metadata"`
+	}
+
+	defaults := ExampleConfig{
+		Name:   "app",
+		Server: ExampleServer{Host: "localhost", Port: 8080, Timeout: 30 * time.Second},
+		Tags:   []string{"a", "b"},
+	}
+	config := defaults
+	manager, err := New(&config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := manager.GenerateExample(&buf); err != nil {
+		t.Fatalf("GenerateExample failed: %v", err)
+	}
+	expected := `name: app # App name
+server:
+  host: localhost # Server host
+  port: 8080 # Server port
+  timeout: 30s # Request timeout
+tags: # Tags
+  - a
+  - b
+metadata: {} # Metadata
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	// The example loads back into the same values.
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(configPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write example: %v", err)
+	}
+	loaded := ExampleConfig{}
+	loader, err := New(&loaded, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	if err := loader.LoadFile(configPath); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	// The example writes the nil map as an empty one.
+	defaults.Metadata = map[string]string{}
+	if !reflect.DeepEqual(loaded, defaults) {
+		t.Errorf("Expected loaded config %+v, got %+v", defaults, loaded)
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandEnv replaces ${VAR} and $VAR in the scalar values of a config file with environment variables.
+// $$ is a literal $. Unset variables are empty, or an error if strict is true.
+func expandEnv(node *yaml.Node, path []string, strict bool) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := expandEnv(child, path, strict); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := append(path[:len(path):len(path)], node.Content[i].Value)
+			if err := expandEnv(node.Content[i+1], childPath, strict); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			childPath := append(path[:len(path):len(path)], fmt.Sprint(i))
+			if err := expandEnv(child, childPath, strict); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		var unset string
+		value := os.Expand(node.Value, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			v, ok := os.LookupEnv(name)
+			if !ok && unset == "" {
+				unset = name
+			}
+			return v
+		})
+		if strict && unset != "" {
+			return fmt.Errorf("could not expand config file: key %s: environment variable %s is not set", strings.Join(path, "."), unset)
+		}
+		node.Value = value
+		// Let yaml resolve the type of unquoted values again, e.g. port: ${PORT}.
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithEnvExpansion(t *testing.T) {
+	type ExpandedConfig struct {
+		URL      string   `name:"url" description:"URL"`
+		Port     int      `name:"port" description:"Port"`
+		Password string   `name:"password" description:"Password"`
+		Hosts    []string `name:"hosts" description:"Hosts"`
+	}
+
+	t.Setenv("GOHELPERS_TEST_URL", "postgres://db")
+	t.Setenv("GOHELPERS_TEST_PORT", "5432")
+	t.Setenv("GOHELPERS_TEST_HOST", "example.com")
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		Options     []Option
+		Expected    ExpandedConfig
+		ExpectError string
+	}{
+		{
+			Name:       "ResolvesVariables",
+			ConfigData: "url: \"${GOHELPERS_TEST_URL}/app\"\nport: ${GOHELPERS_TEST_PORT}\nhosts: [\"$GOHELPERS_TEST_HOST\"]\n",
+			Options:    []Option{WithEnvExpansion()},
+			Expected:   ExpandedConfig{URL: "postgres://db/app", Port: 5432, Hosts: []string{"example.com"}},
+		},
+		{
+			Name:       "EscapedDollar",
+			ConfigData: `password: "pa$$word"`,
+			Options:    []Option{WithEnvExpansion()},
+			Expected:   ExpandedConfig{Password: "pa$word"},
+		},
+		{
+			Name:       "UnsetVariableIsEmpty",
+			ConfigData: `url: "${GOHELPERS_TEST_UNSET}"`,
+			Options:    []Option{WithEnvExpansion()},
+			Expected:   ExpandedConfig{},
+		},
+		{
+			Name:        "UnsetVariableIsError",
+			ConfigData:  `url: "${GOHELPERS_TEST_UNSET}"`,
+			Options:     []Option{WithStrictEnvExpansion()},
+			ExpectError: "could not expand config file: key url: environment variable GOHELPERS_TEST_UNSET is not set",
+		},
+		{
+			Name:       "WithoutOption",
+			ConfigData: `url: "${GOHELPERS_TEST_URL}"`,
+			Expected:   ExpandedConfig{URL: "${GOHELPERS_TEST_URL}"},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &ExpandedConfig{}
+			manager, err := New(config, "", test.Options...)
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			err = parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), nil)
+			if test.ExpectError != "" {
+				if err == nil || err.Error() != test.ExpectError {
+					t.Errorf("Expected error '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// field is a tagged struct field along with where it lives in the flagset and the config file.
+type field struct {
+	// name is the full flag name, including the prefixes of parent structs.
+	name string
+	// path is the key path of the field in the config file.
+	path []string
+	// structField is the reflected struct field.
+	structField reflect.StructField
+	// value is the settable value of the field.
+	value reflect.Value
+}
+
+// fieldPlan holds the metadata of a tagged struct field that doesn't depend on the struct value,
+// so it's computed once per struct type.
+type fieldPlan struct {
+	// index is the index of the field in its struct.
+	index int
+	// structField is the reflected struct field.
+	structField reflect.StructField
+	// name is the name tag, before normalization.
+	name string
+	// key is the key of the field in the config file.
+	key string
+	// short, description and layout are the values of the tags of the same name,
+	// with the default layout if it has none.
+	short       string
+	description string
+	layout      string
+}
+
+// planKey identifies the plans of a struct type by the tag that names its fields.
+type planKey struct {
+	t       reflect.Type
+	nameTag string
+}
+
+// plans caches the fieldPlans of struct types by planKey.
+var plans sync.Map
+
+// structPlan returns the plans of the exported fields of struct type t that have a nameTag tag,
+// in the order they're declared.
+func structPlan(t reflect.Type, nameTag string) []fieldPlan {
+	key := planKey{t: t, nameTag: nameTag}
+	if cached, ok := plans.Load(key); ok {
+		return cached.([]fieldPlan)
+	}
+	var plan []fieldPlan
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get(nameTag)
+		if !sf.IsExported() || name == "" {
+			continue
+		}
+		layout := sf.Tag.Get("layout")
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		plan = append(plan, fieldPlan{
+			index:       i,
+			structField: sf,
+			name:        name,
+			key:         yamlKey(sf),
+			short:       sf.Tag.Get("short"),
+			description: sf.Tag.Get("description"),
+			layout:      layout,
+		})
+	}
+	cached, _ := plans.LoadOrStore(key, plan)
+	return cached.([]fieldPlan)
+}
+
+// walkFields calls fn for every tagged field of v, recursing into nested structs.
+// Nested structs are passed to fn before their own fields.
+// If normalize is not nil, names are normalized the same way as by processStruct.
+func walkFields(nameTag string, normalize func(string) string, v reflect.Value, prefix string, path []string, fn func(f field) error) error {
+	for _, plan := range structPlan(v.Type(), nameTag) {
+		fieldValue := v.Field(plan.index)
+
+		if !fieldValue.CanSet() {
+			continue
+		}
+
+		name := plan.name
+		if normalize != nil {
+			name = normalize(name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		f := field{
+			name:        name,
+			path:        append(path[:len(path):len(path)], plan.key),
+			structField: plan.structField,
+			value:       fieldValue,
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+
+		if fieldValue.Kind() == reflect.Struct && fieldValue.Type() != timeType {
+			if err := walkFields(nameTag, normalize, fieldValue, f.name, f.path, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// leafFields returns the tagged fields of v that aren't nested structs, in the order they're declared.
+func leafFields(nameTag string, normalize func(string) string, v reflect.Value) ([]field, error) {
+	var fields []field
+	err := walkFields(nameTag, normalize, v, "", nil, func(f field) error {
+		if f.value.Kind() != reflect.Struct || f.value.Type() == timeType {
+			fields = append(fields, f)
+		}
+		return nil
+	})
+	return fields, err
+}
+
+// cloneValue returns a copy of v that doesn't share the elements of slices and maps with it.
+func cloneValue(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	switch {
+	case v.Kind() == reflect.Slice && !v.IsNil():
+		c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		reflect.Copy(c, v)
+	case v.Kind() == reflect.Map && !v.IsNil():
+		c.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), iter.Value())
+		}
+	default:
+		c.Set(v)
+	}
+	return c
+}
+
+// yamlKey returns the key that yaml uses for the struct field.
+func yamlKey(sf reflect.StructField) string {
+	key, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+	if key == "" {
+		key = strings.ToLower(sf.Name)
+	}
+	return key
+}
+
+// lookupNode returns the node at path in a yaml document, or nil if there isn't one.
+func lookupNode(doc *yaml.Node, path []string) *yaml.Node {
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// parentNode returns the mapping node that holds the last key of path in a yaml document, or nil if there isn't one.
+func parentNode(doc *yaml.Node, path []string) *yaml.Node {
+	parent := lookupNode(doc, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return nil
+	}
+	return parent
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWalkFields(t *testing.T) {
+	type Inner struct {
+		Value string `name:"value" yaml:"val"`
+	}
+	type Outer struct {
+		Name     string `name:"name"`
+		Untagged string
+		Inner    Inner `name:"inner" yaml:"nested,omitempty"`
+	}
+
+	config := &Outer{}
+	var names, paths []string
+	err := walkFields("name", nil, reflect.ValueOf(config).Elem(), "", nil, func(f field) error {
+		names = append(names, f.name)
+		paths = append(paths, strings.Join(f.path, "."))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedNames := []string{"name", "inner", "inner.value"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("Expected names %v, got %v", expectedNames, names)
+	}
+	expectedPaths := []string{"name", "nested", "nested.val"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected paths %v, got %v", expectedPaths, paths)
+	}
+}
+
+func TestLookupNode(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("server:\n  host: localhost\nname: test\n"), &doc); err != nil {
+		t.Fatalf("Failed to parse yaml: %v", err)
+	}
+
+	for _, test := range []struct {
+		Name     string
+		Path     []string
+		Expected string
+		Found    bool
+	}{
+		{Name: "TopLevelKey", Path: []string{"name"}, Expected: "test", Found: true},
+		{Name: "NestedKey", Path: []string{"server", "host"}, Expected: "localhost", Found: true},
+		{Name: "MissingKey", Path: []string{"server", "port"}},
+		{Name: "PathThroughScalar", Path: []string{"name", "value"}},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			node := lookupNode(&doc, test.Path)
+			if !test.Found {
+				if node != nil {
+					t.Errorf("Expected no node, got %v", node.Value)
+				}
+				return
+			}
+			if node == nil || node.Value != test.Expected {
+				t.Errorf("Expected node with value %q, got %v", test.Expected, node)
+			}
+		})
+	}
+
+	if lookupNode(&yaml.Node{}, []string{"name"}) != nil {
+		t.Error("Expected no node in an empty document")
+	}
+}
+
+func TestStructPlan(t *testing.T) {
+	type PlanConfig struct {
+		Name     string `name:"name" short:"n" description:"Name"`
+		Untagged string
+		hidden   string `name:"hidden"`
+		Created  string `name:"created" yaml:"created_at" layout:"2006-01-02"`
+	}
+	_ = PlanConfig{}.hidden
+
+	typ := reflect.TypeOf(PlanConfig{})
+	plan := structPlan(typ, "name")
+	if len(plan) != 2 {
+		t.Fatalf("Expected 2 planned fields, got %d", len(plan))
+	}
+	if p := plan[0]; p.index != 0 || p.name != "name" || p.key != "name" || p.short != "n" || p.description != "Name" || p.layout != time.RFC3339 {
+		t.Errorf("Unexpected plan for Name: %+v", p)
+	}
+	if p := plan[1]; p.index != 3 || p.key != "created_at" || p.layout != "2006-01-02" {
+		t.Errorf("Unexpected plan for Created: %+v", p)
+	}
+	if again := structPlan(typ, "name"); &again[0] != &plan[0] {
+		t.Error("Expected the plan to be cached")
+	}
+	if other := structPlan(typ, "yaml"); len(other) != 1 || other[0].name != "created_at" {
+		t.Errorf("Expected a separate plan per name tag, got %+v", other)
+	}
+}
+
+func BenchmarkNew(b *testing.B) {
+	b.Run("Cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := New(&ComplexConfig{}, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			plans.Clear()
+			if _, err := New(&ComplexConfig{}, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// nestFlattened moves the keys of nested structs tagged flatten:"true", which the config file writes at the level
+// of their parent, under the key of the struct, so the file can be decoded like any other nested struct.
+func (m *Manager) nestFlattened(doc *yaml.Node) error {
+	// Parent structs are visited first, so the keys of flattened structs in flattened structs are moved twice.
+	return walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if f.value.Kind() != reflect.Struct || f.value.Type() == timeType || f.structField.Tag.Get("flatten") != "true" {
+			return nil
+		}
+		parent := parentNode(doc, f.path)
+		if parent == nil {
+			return nil
+		}
+		key := f.path[len(f.path)-1]
+		nested := removeKey(parent, key)
+		if nested == nil || nested.Kind != yaml.MappingNode {
+			nested = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for _, k := range m.flattenedKeys(f.value.Type()) {
+			if value := removeKey(parent, k); value != nil {
+				nested.Content = append(nested.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+			}
+		}
+		if len(nested.Content) > 0 {
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, nested)
+		}
+		return nil
+	})
+}
+
+// flattenedKeys returns the keys that the fields of struct type t have in the config file when it's flattened,
+// including those of the structs it flattens itself.
+func (m *Manager) flattenedKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get(m.nameTag) == "" {
+			continue
+		}
+		if sf.Type.Kind() == reflect.Struct && sf.Type != timeType && sf.Tag.Get("flatten") == "true" {
+			keys = append(keys, m.flattenedKeys(sf.Type)...)
+			continue
+		}
+		keys = append(keys, yamlKey(sf))
+	}
+	return keys
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseConfigurationFlatten(t *testing.T) {
+	type TLS struct {
+		Cert string `name:"cert" description:"Certificate file"`
+	}
+	type FlatServer struct {
+		Host string `name:"host" description:"Server host"`
+		Port int    `name:"port" description:"Server port"`
+		TLS  TLS    `name:"tls" flatten:"true"`
+	}
+	type FlatConfig struct {
+		Name   string     `name:"name" description:"App name"`
+		Server FlatServer `name:"server" flatten:"true"`
+	}
+
+	for _, test := range []struct {
+		Name       string
+		ConfigData string
+		CmdArgs    []string
+		Expected   FlatConfig
+	}{
+		{
+			Name:       "FromFlatKeys",
+			ConfigData: "name: app\nhost: example.com\nport: 8080\ncert: server.pem\n",
+			Expected:   FlatConfig{Name: "app", Server: FlatServer{Host: "example.com", Port: 8080, TLS: TLS{Cert: "server.pem"}}},
+		},
+		{
+			Name:       "FlagsArePrefixed",
+			ConfigData: "host: example.com\nport: 8080\n",
+			CmdArgs:    []string{"--server.port", "9090", "--server.tls.cert", "other.pem"},
+			Expected:   FlatConfig{Server: FlatServer{Host: "example.com", Port: 9090, TLS: TLS{Cert: "other.pem"}}},
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &FlatConfig{}
+			manager, err := New(config, "")
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			if err := parseWithArgs(t, manager, createTempConfigFile(t, test.ConfigData), test.CmdArgs); err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+			if !reflect.DeepEqual(*config, test.Expected) {
+				t.Errorf("Expected config %+v, got %+v", test.Expected, *config)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaFlatten(t *testing.T) {
+	type FlatServer struct {
+		Host string `name:"host" description:"Server host" required:"true"`
+	}
+	type FlatConfig struct {
+		Name   string     `name:"name" description:"App name"`
+		Server FlatServer `name:"server" flatten:"true"`
+	}
+
+	manager, err := New(&FlatConfig{}, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	data, err := manager.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to unmarshal schema: %v", err)
+	}
+	var keys []string
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if expected := []string{"host", "name"}; !slices.Equal(keys, expected) {
+		t.Errorf("Expected properties %v, got %v", expected, keys)
+	}
+	if expected := []string{"host"}; !slices.Equal(schema.Required, expected) {
+		t.Errorf("Expected required %v, got %v", expected, schema.Required)
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// interpolator resolves {flag.name} references in string flags.
+type interpolator struct {
+	flags    *pflag.FlagSet
+	resolved map[string]bool
+	// stack holds the flags currently being resolved, to detect cycles.
+	stack []string
+}
+
+// interpolate resolves references in every string flag of the flagset.
+func interpolate(fs *pflag.FlagSet) error {
+	in := &interpolator{
+		flags:    fs,
+		resolved: make(map[string]bool),
+	}
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err == nil {
+			err = in.resolve(f)
+		}
+	})
+	return err
+}
+
+// resolve replaces the references in a string flag with the values of the referenced flags.
+func (in *interpolator) resolve(f *pflag.Flag) error {
+	if f.Value.Type() != "string" || f.Name == "config" || in.resolved[f.Name] || isUnset(f.Value) {
+		return nil
+	}
+	for i, name := range in.stack {
+		if name == f.Name {
+			return fmt.Errorf("interpolation cycle: %s", strings.Join(append(in.stack[i:], f.Name), " -> "))
+		}
+	}
+	in.stack = append(in.stack, f.Name)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+	}()
+
+	raw := plainString(f.Value)
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '{' && i+1 < len(raw) && raw[i+1] == '{':
+			b.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(raw) && raw[i+1] == '}':
+			b.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(raw[i:], '}')
+			if end == -1 {
+				return fmt.Errorf("could not interpolate flag %s: unterminated reference", f.Name)
+			}
+			name := raw[i+1 : i+end]
+			ref := in.flags.Lookup(name)
+			if ref == nil {
+				return fmt.Errorf("could not interpolate flag %s: unknown flag %s", f.Name, name)
+			}
+			if err := in.resolve(ref); err != nil {
+				return err
+			}
+			b.WriteString(plainString(ref.Value))
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if err := setPlainString(f.Value, b.String()); err != nil {
+		return fmt.Errorf("could not interpolate flag %s: %w", f.Name, err)
+	}
+	in.resolved[f.Name] = true
+	return nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithInterpolation(t *testing.T) {
+	type InterpolatedServer struct {
+		Host string `name:"host" description:"Server host"`
+		Port int    `name:"port" description:"Server port"`
+	}
+	type InterpolatedConfig struct {
+		Server  InterpolatedServer `name:"server"`
+		BaseURL string             `name:"base-url" yaml:"base_url" description:"Base URL"`
+		Health  string             `name:"health" description:"Health URL"`
+		Other   string             `name:"other" description:"Other value"`
+	}
+
+	for _, test := range []struct {
+		Name        string
+		ConfigData  string
+		CmdArgs     []string
+		ExpectError string
+		Validate    func(t *testing.T, config *InterpolatedConfig)
+	}{
+		{
+			Name: "ResolvesReferences",
+			ConfigData: `
+server:
+  host: "example.com"
+  port: 8443
+base_url: "https://{server.host}:{server.port}"
+health: "{base-url}/health"
+other: "{{literal}}"
+`,
+			Validate: func(t *testing.T, config *InterpolatedConfig) {
+				if config.BaseURL != "https://example.com:8443" {
+					t.Errorf("Expected base_url 'https://example.com:8443', got '%s'", config.BaseURL)
+				}
+				if config.Health != "https://example.com:8443/health" {
+					t.Errorf("Expected health 'https://example.com:8443/health', got '%s'", config.Health)
+				}
+				if config.Other != "{literal}" {
+					t.Errorf("Expected other '{literal}', got '%s'", config.Other)
+				}
+			},
+		},
+		{
+			Name: "ResolvesFlagValues",
+			ConfigData: `
+server:
+  host: "example.com"
+base_url: "http://{server.host}"
+`,
+			CmdArgs: []string{"--server.host", "localhost"},
+			Validate: func(t *testing.T, config *InterpolatedConfig) {
+				if config.BaseURL != "http://localhost" {
+					t.Errorf("Expected base_url 'http://localhost', got '%s'", config.BaseURL)
+				}
+			},
+		},
+		{
+			Name: "CycleErrors",
+			ConfigData: `
+base_url: "{health}"
+health: "{other}"
+other: "{base-url}"
+`,
+			ExpectError: "interpolation cycle: base-url -> health -> other -> base-url",
+		},
+		{
+			Name:        "UnknownReferenceErrors",
+			ConfigData:  `base_url: "{missing}"`,
+			ExpectError: "unknown flag missing",
+		},
+		{
+			Name:        "UnterminatedReferenceErrors",
+			ConfigData:  `base_url: "{server.host"`,
+			ExpectError: "unterminated reference",
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			config := &InterpolatedConfig{}
+			manager, err := New(config, "", WithInterpolation())
+			if err != nil {
+				t.Fatalf("Failed to create manager: %v", err)
+			}
+
+			configPath := createTempConfigFile(t, test.ConfigData)
+			err = parseWithArgs(t, manager, configPath, test.CmdArgs)
+			if test.ExpectError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.ExpectError) {
+					t.Errorf("Expected error containing '%s', got: %v", test.ExpectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfiguration failed: %v", err)
+			}
+
+			test.Validate(t, config)
+		})
+	}
+}
+
+func TestWithoutInterpolation(t *testing.T) {
+	config := &SimpleConfig{}
+	manager, err := New(config, "")
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	configPath := createTempConfigFile(t, `name: "{port}"`)
+	if err := parseWithArgs(t, manager, configPath, nil); err != nil {
+		t.Fatalf("ParseConfiguration failed: %v", err)
+	}
+
+	if config.Name != "{port}" {
+		t.Errorf("Expected name '{port}' without interpolation, got '%s'", config.Name)
+	}
+}
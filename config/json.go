@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// renameJSONKeys renames the keys of a JSON config file to the yaml keys of the fields of type t they belong to,
+// so the file is decoded like encoding/json would: by json tag, else by field name ignoring case.
+// Keys that are already yaml keys are kept, the keys of fields tagged json:"-" are removed,
+// and keys that match no field are left for the decoder.
+func renameJSONKeys(node *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.DocumentNode {
+		for _, content := range node.Content {
+			renameJSONKeys(content, t)
+		}
+		return
+	}
+	switch {
+	case t.Kind() == reflect.Struct && t != timeType && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if sf, ok := jsonField(t, node.Content[i].Value); ok {
+				node.Content[i].Value = yamlKey(sf)
+				renameJSONKeys(node.Content[i+1], sf.Type)
+			} else if jsonIgnored(t, node.Content[i].Value) {
+				// Fields tagged json:"-" can't be set from a JSON file, even by their yaml key.
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+				i -= 2
+			}
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			renameJSONKeys(item, t.Elem())
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			renameJSONKeys(node.Content[i], t.Elem())
+		}
+	}
+}
+
+// jsonField returns the field of struct type t that a JSON key belongs to, including the fields of the structs
+// tagged flatten:"true", which the config file writes at the level of their parent.
+// Like encoding/json, exact json tag matches come first, then field names ignoring case.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var folded *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+			continue
+		case name == key || yamlKey(sf) == key:
+			return sf, true
+		case name == "" && folded == nil && strings.EqualFold(sf.Name, key):
+			folded = &sf
+		}
+		if sf.Type.Kind() == reflect.Struct && sf.Tag.Get("flatten") == "true" {
+			if nested, ok := jsonField(sf.Type, key); ok {
+				return nested, true
+			}
+		}
+	}
+	if folded != nil {
+		return *folded, true
+	}
+	return reflect.StructField{}, false
+}
+
+// jsonIgnored returns whether key is the yaml key of a field of struct type t tagged json:"-",
+// including the fields of the structs tagged flatten:"true".
+func jsonIgnored(t reflect.Type, key string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if sf.Tag.Get("json") == "-" && yamlKey(sf) == key {
+			return true
+		}
+		if sf.Type.Kind() == reflect.Struct && sf.Tag.Get("flatten") == "true" && jsonIgnored(sf.Type, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import "gopkg.in/yaml.v3"
+
+// Option configures a Manager.
+type Option func(*Manager)
+
+// WithWarningHandler sets the function that receives non-fatal warnings, such as deprecated config keys.
+// By default, warnings are logged with the default slog logger.
+func WithWarningHandler(fn func(msg string)) Option {
+	return func(m *Manager) {
+		m.warn = fn
+	}
+}
+
+// WithSetFlag registers a repeatable --set flag that assigns values by flag name, e.g. --set server.port=9090.
+// Assignments are applied after the config file and are overridden by the flags themselves.
+func WithSetFlag() Option {
+	return func(m *Manager) {
+		m.setFlag = true
+	}
+}
+
+// WithSourceTracking records where each value came from during ParseConfiguration.
+// Use Manager.SourceOf to query the source of a flag.
+func WithSourceTracking() Option {
+	return func(m *Manager) {
+		m.sources = make(map[string]string)
+	}
+}
+
+// WithDescriptions sets the usage strings of flags by name, taking precedence over the description tag.
+// This allows help text to be managed outside of the struct, for example for translations.
+func WithDescriptions(descriptions map[string]string) Option {
+	return func(m *Manager) {
+		m.descriptions = descriptions
+	}
+}
+
+// WithInterpolation resolves {flag.name} references in string values after the config file and flags are merged.
+// Use {{ and }} for literal braces.
+func WithInterpolation() Option {
+	return func(m *Manager) {
+		m.interpolate = true
+	}
+}
+
+// WithNameNormalizer rewrites each name tag, including those of nested structs, before it's used in a flag name.
+// For example, strings.ToLower makes all generated flags lowercase.
+// New returns an error if two fields normalize to the same flag name.
+func WithNameNormalizer(fn func(string) string) Option {
+	return func(m *Manager) {
+		m.normalize = fn
+	}
+}
+
+// WithYAMLDecoderOptions configures the yaml decoder used for the config file, for example with KnownFields.
+func WithYAMLDecoderOptions(opts ...func(*yaml.Decoder)) Option {
+	return func(m *Manager) {
+		m.decoderOpts = append(m.decoderOpts, opts...)
+	}
+}
+
+// WithActiveEnvironmentKey merges an environment's values over the rest of the config file.
+// The top level activeKey names the environment, which is looked up in the top level environmentsKey map.
+// Both keys are removed before the file is decoded, so the target doesn't need fields for them.
+func WithActiveEnvironmentKey(activeKey, environmentsKey string) Option {
+	return func(m *Manager) {
+		m.activeEnvironmentKey = activeKey
+		m.environmentsKey = environmentsKey
+	}
+}
+
+// WithDefaults copies the non-zero fields of defaults into the target before the flags are generated,
+// so they become the flag defaults. Defaults must be a struct or pointer to a struct of the same type as the target.
+func WithDefaults(defaults any) Option {
+	return func(m *Manager) {
+		m.defaults = defaults
+	}
+}
+
+// WithIndexedKeys reads lists written as indexed keys in the config file, e.g. tags.0 and tags.1, into slice fields.
+// The elements are ordered by index.
+func WithIndexedKeys() Option {
+	return func(m *Manager) {
+		m.indexedKeys = true
+	}
+}
+
+// WithRedactKeys masks the values of the fields whose flag name contains one of keys, ignoring case,
+// as if they were tagged secret:"true".
+func WithRedactKeys(keys ...string) Option {
+	return func(m *Manager) {
+		m.redactKeys = append(m.redactKeys, keys...)
+	}
+}
+
+// WithTreatEmptyAsUnset ignores empty values of string fields in the config file, e.g. name: "",
+// so the fields keep their defaults. An empty string can then only be set with a flag or --set.
+func WithTreatEmptyAsUnset() Option {
+	return func(m *Manager) {
+		m.emptyAsUnset = true
+	}
+}
+
+// WithEnvExpansion replaces ${VAR} and $VAR in the values of the config file with environment variables
+// before the file is decoded. Unset variables are empty. Use $$ for a literal $.
+func WithEnvExpansion() Option {
+	return func(m *Manager) {
+		m.expandEnv = true
+	}
+}
+
+// WithStrictEnvExpansion is like WithEnvExpansion, but returns an error for unset variables.
+func WithStrictEnvExpansion() Option {
+	return func(m *Manager) {
+		m.expandEnv = true
+		m.expandEnvStrict = true
+	}
+}
+
+// WithUnknownFlags sets whether unknown flags are ignored when parsing, rather than being an error.
+// It applies to FlagSet().Parse and ParseArgs; cobra commands have their own setting, FParseErrWhitelist.
+func WithUnknownFlags(ignore bool) Option {
+	return func(m *Manager) {
+		m.flags.ParseErrorsAllowlist.UnknownFlags = ignore
+	}
+}
+
+// WithPrecedence sets the order in which the sources are applied, from lowest to highest precedence,
+// e.g. SourceFlag, SourceFile to let the config file override flags. Sources left out are ignored entirely.
+// The default is SourceFile, SourceEnv, SourceFlag.
+func WithPrecedence(order ...Source) Option {
+	return func(m *Manager) {
+		// Keep an empty order, which ignores every source, apart from the default.
+		m.precedence = append([]Source{}, order...)
+	}
+}
+
+// WithMarshaler replaces the marshaler that DumpConfig uses for its format, for example with one that keeps
+// comments or orders keys. It's called with the values by key as nested map[string]any.
+func WithMarshaler(marshal func(any) ([]byte, error)) Option {
+	return func(m *Manager) {
+		m.marshal = marshal
+	}
+}
+
+// WithEmbeddedDefaults reads data, a config file in the format "yaml" or "json", into the target before the flags
+// are generated, so its values become the defaults that the config file and flags override.
+// The config file is then optional: ParseConfiguration ignores it if it doesn't exist, unless it was set with --config.
+// This suits single binaries that embed their default config, e.g. with go:embed.
+func WithEmbeddedDefaults(data []byte, format string) Option {
+	return func(m *Manager) {
+		m.embedded = data
+		m.embeddedFormat = format
+	}
+}
+
+// WithPrintConfigFlag registers a --print-config flag that makes ParseConfiguration print the resolved configuration
+// to stdout like DumpConfig, as yaml or with --print-config=json, and return ErrConfigPrinted, after which the caller
+// should exit.
+func WithPrintConfigFlag() Option {
+	return func(m *Manager) {
+		m.printConfigFlag = true
+	}
+}
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// jsonSchemaDraft is the JSON Schema version of the schema returned by JSONSchema.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// JSONSchema returns a Draft-07 JSON Schema of the config file, for example for validation in editors.
+// Nested structs become nested objects unless they're tagged flatten:"true", descriptions are the flag usages,
+// enum and choices tags with comma separated values, e.g. enum:"debug,info", become enums, min and max tags become minimum and
+// maximum and fields tagged required:"true" are required.
+// Values that the config file writes as strings, such as durations, times and byte slices, are strings.
+func (m *Manager) JSONSchema() ([]byte, error) {
+	schema, err := m.objectSchema(reflect.ValueOf(m.target).Elem(), "", true)
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = jsonSchemaDraft
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// objectSchema returns the schema of the struct v whose fields have flags under prefix if withFlags is true.
+func (m *Manager) objectSchema(v reflect.Value, prefix string, withFlags bool) (map[string]any, error) {
+	properties := make(map[string]any)
+	var required []string
+	err := walkFields(m.nameTag, m.normalize, v, prefix, nil, func(f field) error {
+		// Nested fields are added by the schema of their struct.
+		if len(f.path) != 1 {
+			return nil
+		}
+		s, err := m.fieldSchema(f, withFlags)
+		if err != nil {
+			return err
+		}
+		if f.value.Kind() == reflect.Struct && f.value.Type() != timeType && f.structField.Tag.Get("flatten") == "true" {
+			// The config file writes the fields of flattened structs at this level.
+			for key, property := range s["properties"].(map[string]any) {
+				properties[key] = property
+			}
+			if r, ok := s["required"].([]string); ok {
+				required = append(required, r...)
+			}
+			return nil
+		}
+		properties[f.path[0]] = s
+		if f.structField.Tag.Get("required") == "true" {
+			required = append(required, f.path[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// fieldSchema returns the schema of a field, including its description and enum.
+func (m *Manager) fieldSchema(f field, withFlags bool) (map[string]any, error) {
+	var schema map[string]any
+	switch {
+	case isValue(f.value):
+		schema = map[string]any{"type": "string"}
+	case f.value.Kind() == reflect.Struct && f.value.Type() != timeType:
+		var err error
+		if schema, err = m.objectSchema(f.value, f.name, withFlags); err != nil {
+			return nil, err
+		}
+	default:
+		var err error
+		if schema, err = m.typeSchema(f.value.Type()); err != nil {
+			return nil, err
+		}
+	}
+
+	description := f.structField.Tag.Get("description")
+	if withFlags {
+		if fl := m.flags.Lookup(f.name); fl != nil && fl.Usage != "" {
+			description = fl.Usage
+		}
+	}
+	if description != "" {
+		schema["description"] = description
+	}
+
+	var values []any
+	if enum := f.structField.Tag.Get("enum"); enum != "" {
+		for _, s := range strings.Split(enum, ",") {
+			// Read the values like the config file, so numbers and bools keep their types.
+			var value any
+			if err := yaml.Unmarshal([]byte(strings.TrimSpace(s)), &value); err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	} else if choices, ok := f.structField.Tag.Lookup("choices"); ok {
+		for _, choice := range splitChoices(choices) {
+			values = append(values, choice)
+		}
+	}
+	if values != nil {
+		// The values of slices are their elements.
+		if items, ok := schema["items"].(map[string]any); ok {
+			items["enum"] = values
+		} else {
+			schema["enum"] = values
+		}
+	}
+	for tag, keyword := range map[string]string{"min": "minimum", "max": "maximum"} {
+		if limit := f.structField.Tag.Get(tag); limit != "" {
+			value, err := strconv.ParseFloat(limit, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s tag of field %s: %s is not a number", tag, f.structField.Name, limit)
+			}
+			schema[keyword] = value
+		}
+	}
+	return schema, nil
+}
+
+// typeSchema returns the schema of a value of type t, which isn't a nested struct field.
+func (m *Manager) typeSchema(t reflect.Type) (map[string]any, error) {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t == durationType:
+		return map[string]any{"type": "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return m.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string"}, nil
+		}
+		items, err := m.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := m.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		// Structs in maps and slices have no flags.
+		return m.objectSchema(reflect.New(t).Elem(), "", false)
+	}
+	// Any value is allowed for interfaces and other types the schema can't describe.
+	return map[string]any{}, nil
+}
+
+// isValue returns whether a field implements pflag.Value, in which case it's written as a string.
+func isValue(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(pflag.Value)
+	return ok
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestManagerJSONSchema(t *testing.T) {
+	type Listener struct {
+		Address string `name:"address"`
+	}
+	type SchemaServer struct {
+		Host    string        `name:"host" description:"Server host" required:"true"`
+		Port    int           `name:"port" description:"Server port" enum:"80,443"`
+		Timeout time.Duration `name:"timeout" description:"Request timeout"`
+	}
+	type SchemaConfig struct {
+		Level     string              `name:"level" description:"Log level" enum:"debug,info"`
+		Debug     bool                `name:"debug" description:"Debug mode"`
+		Format    string              `name:"format" description:"Log format" choices:"json,text"`
+		Ratio     float64             `name:"ratio" description:"Ratio" min:"0" max:"1"`
+		Server    SchemaServer        `name:"server"`
+		Tags      []string            `name:"tags" description:"Tags" enum:"a,b,c"`
+		Labels    map[string]string   `name:"labels" description:"Labels"`
+		Listeners map[string]Listener `name:"listeners"`
+		Key       []byte              `name:"key" description:"Key"`
+	}
+
+	manager, err := New(&SchemaConfig{}, "", WithDescriptions(map[string]string{"debug": "Enable debug mode"}))
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	data, err := manager.JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to unmarshal schema: %v", err)
+	}
+
+	var expected map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"level": {"type": "string", "description": "Log level", "enum": ["debug", "info"]},
+			"debug": {"type": "boolean", "description": "Enable debug mode"},
+			"format": {"type": "string", "description": "Log format (one of: json, text)", "enum": ["json", "text"]},
+			"ratio": {"type": "number", "description": "Ratio", "minimum": 0, "maximum": 1},
+			"server": {
+				"type": "object",
+				"properties": {
+					"host": {"type": "string", "description": "Server host"},
+					"port": {"type": "integer", "description": "Server port", "enum": [80, 443]},
+					"timeout": {"type": "string", "description": "Request timeout"}
+				},
+				"required": ["host"]
+			},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b", "c"]}, "description": "Tags"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Labels"},
+			"listeners": {
+				"type": "object",
+				"additionalProperties": {"type": "object", "properties": {"address": {"type": "string"}}}
+			},
+			"key": {"type": "string", "description": "Key"}
+		}
+	}`), &expected); err != nil {
+		t.Fatalf("Failed to unmarshal expected schema: %v", err)
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("Expected schema %s, got %s", mustJSON(t, expected), data)
+	}
+
+	configData := `
+level: debug
+debug: true
+ratio: 0.5
+server:
+  host: example.com
+  port: 443
+  timeout: 30s
+tags: ["a", "b"]
+labels:
+  env: test
+listeners:
+  public:
+    address: ":443"
+key: "deadbeef"
+`
+	var config any
+	if err := yaml.Unmarshal([]byte(configData), &config); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if err := validateSchema(schema, config); err != nil {
+		t.Errorf("Expected config to conform to the schema: %v", err)
+	}
+	if err := validateSchema(schema, map[string]any{"server": map[string]any{"port": 8080}}); err == nil {
+		t.Error("Expected config with a missing required key and an invalid enum value to be rejected")
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	return string(data)
+}
+
+// validateSchema checks value against the subset of JSON Schema that JSONSchema generates.
+func validateSchema(schema map[string]any, value any) error {
+	if enum, ok := schema["enum"].([]any); ok {
+		// Compare the printed values, so the numbers of the schema and the config file are equal.
+		if !slices.ContainsFunc(enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+			return fmt.Errorf("value %v is not one of %v", value, enum)
+		}
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected object, got %T", value)
+		}
+		required, _ := schema["required"].([]any)
+		for _, key := range required {
+			if _, ok := object[key.(string)]; !ok {
+				return fmt.Errorf("missing required key %s", key)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for key, v := range object {
+			s, ok := properties[key].(map[string]any)
+			if !ok {
+				s, ok = schema["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				return fmt.Errorf("unexpected key %s", key)
+			}
+			if err := validateSchema(s, v); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("expected array, got %T", value)
+		}
+		for i, v := range array {
+			if err := validateSchema(schema["items"].(map[string]any), v); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected string, got %T", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected boolean, got %T", value)
+		}
+	case "integer":
+		if _, ok := value.(int); !ok {
+			return fmt.Errorf("expected integer, got %T", value)
+		}
+	case "number":
+		switch value.(type) {
+		case int, float64:
+		default:
+			return fmt.Errorf("expected number, got %T", value)
+		}
+	}
+	return nil
+}
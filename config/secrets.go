@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maskedValue replaces the values of fields tagged secret:"true".
+const maskedValue = "******"
+
+// isSecret returns whether the value of a field must be masked: it's tagged secret:"true"
+// or its flag name contains one of the keys passed to WithRedactKeys, ignoring case.
+func (m *Manager) isSecret(f field) bool {
+	if f.structField.Tag.Get("secret") == "true" {
+		return true
+	}
+	name := strings.ToLower(f.name)
+	for _, key := range m.redactKeys {
+		if key != "" && strings.Contains(name, strings.ToLower(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFlags returns the flag names of the fields whose values must be masked.
+func (m *Manager) secretFlags() map[string]bool {
+	secrets := make(map[string]bool)
+	// walkFields only returns the errors of its callback, and this one has none.
+	_ = walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if m.isSecret(f) {
+			secrets[f.name] = true
+		}
+		return nil
+	})
+	return secrets
+}
+
+// flagSecrets returns the current values of the secret flags.
+func (m *Manager) flagSecrets(secretFlags map[string]bool) []string {
+	var secrets []string
+	for name := range secretFlags {
+		if f := m.flags.Lookup(name); f != nil {
+			// Base64 fields are masked in their encoded and decoded form.
+			secrets = append(secrets, f.Value.String(), plainString(f.Value))
+		}
+	}
+	return secrets
+}
+
+// fileSecrets returns the values of the fields that must be masked in a config file.
+func (m *Manager) fileSecrets(doc *yaml.Node) []string {
+	var secrets []string
+	_ = walkFields(m.nameTag, m.normalize, reflect.ValueOf(m.target).Elem(), "", nil, func(f field) error {
+		if !m.isSecret(f) {
+			return nil
+		}
+		if node := lookupNode(doc, f.path); node != nil && node.Kind == yaml.ScalarNode {
+			secrets = append(secrets, node.Value)
+			if f.structField.Tag.Get("encoding") == "base64" {
+				if decoded, err := base64.StdEncoding.DecodeString(node.Value); err == nil {
+					secrets = append(secrets, string(decoded))
+				}
+			}
+		}
+		return nil
+	})
+	return secrets
+}
+
+// maskSecrets returns err with the secrets in its message masked.
+// If the message contains a secret, the returned error no longer wraps err, so the secret can't be retrieved from it.
+func maskSecrets(err error, secrets []string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	for _, secret := range secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, maskedValue)
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
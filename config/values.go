@@ -0,0 +1,689 @@
+// SPDX-FileCopyrightText: Copyright 2026 Krishna Iyer (www.ekri.sh)
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	longDurationUnit = regexp.MustCompile(`([0-9]*\.?[0-9]+)([dw])`)
+)
+
+// timeValue is a pflag.Value for time.Time, parsed with a layout.
+type timeValue struct {
+	value  *time.Time
+	layout string
+}
+
+func newTimeValue(p *time.Time, layout string) *timeValue {
+	return &timeValue{value: p, layout: layout}
+}
+
+func (t *timeValue) Set(s string) error {
+	v, err := time.Parse(t.layout, s)
+	if err != nil {
+		return err
+	}
+	*t.value = v
+	return nil
+}
+
+func (t *timeValue) String() string {
+	if t.value.IsZero() {
+		return ""
+	}
+	return t.value.Format(t.layout)
+}
+
+func (t *timeValue) Type() string {
+	return "time"
+}
+
+// timeSliceValue is a pflag.Value for []time.Time, parsed with a layout.
+// Like pflag's slices, the first Set replaces the default and subsequent calls append.
+type timeSliceValue struct {
+	value   *[]time.Time
+	layout  string
+	changed bool
+}
+
+func newTimeSliceValue(p *[]time.Time, layout string) *timeSliceValue {
+	return &timeSliceValue{value: p, layout: layout}
+}
+
+func (t *timeSliceValue) parse(values []string) ([]time.Time, error) {
+	out := make([]time.Time, len(values))
+	for i, s := range values {
+		v, err := time.Parse(t.layout, strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func (t *timeSliceValue) Set(s string) error {
+	out, err := t.parse(strings.Split(s, ","))
+	if err != nil {
+		return err
+	}
+	if !t.changed {
+		*t.value = out
+	} else {
+		*t.value = append(*t.value, out...)
+	}
+	t.changed = true
+	return nil
+}
+
+func (t *timeSliceValue) String() string {
+	return "[" + strings.Join(t.GetSlice(), ",") + "]"
+}
+
+func (t *timeSliceValue) Type() string {
+	return "timeSlice"
+}
+
+func (t *timeSliceValue) Append(s string) error {
+	out, err := t.parse([]string{s})
+	if err != nil {
+		return err
+	}
+	*t.value = append(*t.value, out...)
+	return nil
+}
+
+func (t *timeSliceValue) Replace(values []string) error {
+	out, err := t.parse(values)
+	if err != nil {
+		return err
+	}
+	*t.value = out
+	return nil
+}
+
+func (t *timeSliceValue) GetSlice() []string {
+	out := make([]string, len(*t.value))
+	for i, v := range *t.value {
+		out[i] = v.Format(t.layout)
+	}
+	return out
+}
+
+// pairSliceValue is a pflag.Value for slices of structs with two string fields, such as a name and a value,
+// set as key=value. It's repeatable: the first Set replaces the default and subsequent calls append.
+type pairSliceValue struct {
+	// value is a pointer to the slice.
+	value   reflect.Value
+	changed bool
+}
+
+func newPairSliceValue(p reflect.Value) *pairSliceValue {
+	return &pairSliceValue{value: p}
+}
+
+// isPairStruct returns whether t is a struct with exactly two exported string fields.
+func isPairStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 {
+		return false
+	}
+	for i := 0; i < 2; i++ {
+		if f := t.Field(i); !f.IsExported() || f.Type.Kind() != reflect.String {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pairSliceValue) parse(values []string) (reflect.Value, error) {
+	out := reflect.MakeSlice(p.value.Elem().Type(), len(values), len(values))
+	for i, s := range values {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%s must be formatted as key=value", s)
+		}
+		out.Index(i).Field(0).SetString(key)
+		out.Index(i).Field(1).SetString(value)
+	}
+	return out, nil
+}
+
+func (p *pairSliceValue) Set(s string) error {
+	out, err := p.parse([]string{s})
+	if err != nil {
+		return err
+	}
+	if !p.changed {
+		p.value.Elem().Set(out)
+	} else {
+		p.value.Elem().Set(reflect.AppendSlice(p.value.Elem(), out))
+	}
+	p.changed = true
+	return nil
+}
+
+func (p *pairSliceValue) String() string {
+	return "[" + strings.Join(p.GetSlice(), ",") + "]"
+}
+
+func (p *pairSliceValue) Type() string {
+	return "pairSlice"
+}
+
+func (p *pairSliceValue) Append(s string) error {
+	out, err := p.parse([]string{s})
+	if err != nil {
+		return err
+	}
+	p.value.Elem().Set(reflect.AppendSlice(p.value.Elem(), out))
+	return nil
+}
+
+func (p *pairSliceValue) Replace(values []string) error {
+	out, err := p.parse(values)
+	if err != nil {
+		return err
+	}
+	p.value.Elem().Set(out)
+	return nil
+}
+
+func (p *pairSliceValue) GetSlice() []string {
+	slice := p.value.Elem()
+	out := make([]string, slice.Len())
+	for i := range out {
+		out[i] = slice.Index(i).Field(0).String() + "=" + slice.Index(i).Field(1).String()
+	}
+	return out
+}
+
+// stringsValue is a pflag.Value for slices of a named string type, such as []Protocol, set like a string slice:
+// comma separated, and the first Set replaces the default while subsequent calls append.
+type stringsValue struct {
+	// value is a pointer to the slice.
+	value   reflect.Value
+	changed bool
+}
+
+func newStringsValue(p reflect.Value) *stringsValue {
+	return &stringsValue{value: p}
+}
+
+func (v *stringsValue) parse(values []string) reflect.Value {
+	out := reflect.MakeSlice(v.value.Elem().Type(), len(values), len(values))
+	for i, s := range values {
+		out.Index(i).SetString(s)
+	}
+	return out
+}
+
+func (v *stringsValue) Set(s string) error {
+	values, err := csv.NewReader(strings.NewReader(s)).Read()
+	if err != nil {
+		return err
+	}
+	if !v.changed {
+		v.value.Elem().Set(v.parse(values))
+	} else {
+		v.value.Elem().Set(reflect.AppendSlice(v.value.Elem(), v.parse(values)))
+	}
+	v.changed = true
+	return nil
+}
+
+func (v *stringsValue) String() string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(v.GetSlice()); err != nil {
+		return ""
+	}
+	w.Flush()
+	return "[" + strings.TrimSuffix(b.String(), "\n") + "]"
+}
+
+func (v *stringsValue) Type() string {
+	return "stringSlice"
+}
+
+func (v *stringsValue) Append(s string) error {
+	v.value.Elem().Set(reflect.AppendSlice(v.value.Elem(), v.parse([]string{s})))
+	return nil
+}
+
+func (v *stringsValue) Replace(values []string) error {
+	v.value.Elem().Set(v.parse(values))
+	return nil
+}
+
+func (v *stringsValue) GetSlice() []string {
+	slice := v.value.Elem()
+	out := make([]string, slice.Len())
+	for i := range out {
+		out[i] = slice.Index(i).String()
+	}
+	return out
+}
+
+// elementValue is a pflag.Value for a field of the struct at an index of a slice of structs.
+// It's bound to a copy of the element and writes the copy back on Set, growing the slice if it's shorter,
+// rather than pointing into the slice, since the config file decoder replaces the slice.
+type elementValue struct {
+	// slice is the slice field and holder the copy of its element at index that inner is bound to.
+	slice  reflect.Value
+	index  int
+	holder reflect.Value
+	inner  pflag.Value
+}
+
+// elementSliceValue is an elementValue whose field is a slice, so it can be saved and set back element-wise.
+type elementSliceValue struct {
+	*elementValue
+}
+
+// newElementValue returns a value for a flag of the element at index of slice, whose inner value is bound to holder.
+func newElementValue(slice reflect.Value, index int, holder reflect.Value, inner pflag.Value) pflag.Value {
+	e := &elementValue{slice: slice, index: index, holder: holder, inner: inner}
+	if _, ok := inner.(pflag.SliceValue); ok {
+		return &elementSliceValue{e}
+	}
+	return e
+}
+
+// load copies the element into the holder, or zeroes the holder if the slice has no element at the index.
+func (e *elementValue) load() {
+	if e.index < e.slice.Len() {
+		e.holder.Set(e.slice.Index(e.index))
+	} else {
+		e.holder.Set(reflect.Zero(e.holder.Type()))
+	}
+}
+
+// store copies the holder into the element, appending zero elements up to the index if needed.
+func (e *elementValue) store() {
+	for e.slice.Len() <= e.index {
+		e.slice.Set(reflect.Append(e.slice, reflect.Zero(e.holder.Type())))
+	}
+	e.slice.Index(e.index).Set(e.holder)
+}
+
+func (e *elementValue) Set(s string) error {
+	e.load()
+	if err := e.inner.Set(s); err != nil {
+		return err
+	}
+	e.store()
+	return nil
+}
+
+func (e *elementValue) String() string {
+	e.load()
+	return e.inner.String()
+}
+
+func (e *elementValue) Type() string {
+	return e.inner.Type()
+}
+
+func (e *elementSliceValue) Append(s string) error {
+	e.load()
+	if err := e.inner.(pflag.SliceValue).Append(s); err != nil {
+		return err
+	}
+	e.store()
+	return nil
+}
+
+func (e *elementSliceValue) Replace(values []string) error {
+	e.load()
+	if err := e.inner.(pflag.SliceValue).Replace(values); err != nil {
+		return err
+	}
+	e.store()
+	return nil
+}
+
+func (e *elementSliceValue) GetSlice() []string {
+	e.load()
+	return e.inner.(pflag.SliceValue).GetSlice()
+}
+
+// timeMapValue is a pflag.Value for map[string]time.Time, set as comma separated key=value pairs.
+// Like pflag's maps, the first Set replaces the default and subsequent calls add to it.
+// Set accepts the bracketed form returned by String, so values can be set back.
+type timeMapValue struct {
+	value   *map[string]time.Time
+	layout  string
+	changed bool
+}
+
+func newTimeMapValue(p *map[string]time.Time, layout string) *timeMapValue {
+	return &timeMapValue{value: p, layout: layout}
+}
+
+func (t *timeMapValue) Set(s string) error {
+	out := make(map[string]time.Time)
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("%s must be formatted as key=value", pair)
+		}
+		v, err := time.Parse(t.layout, strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		out[strings.TrimSpace(key)] = v
+	}
+	if !t.changed || *t.value == nil {
+		*t.value = out
+	} else {
+		for k, v := range out {
+			(*t.value)[k] = v
+		}
+	}
+	t.changed = true
+	return nil
+}
+
+func (t *timeMapValue) String() string {
+	pairs := make([]string, 0, len(*t.value))
+	for k, v := range *t.value {
+		pairs = append(pairs, k+"="+v.Format(t.layout))
+	}
+	slices.Sort(pairs)
+	return "[" + strings.Join(pairs, ",") + "]"
+}
+
+func (t *timeMapValue) Type() string {
+	return "stringToTime"
+}
+
+// parseLongDuration parses a duration like time.ParseDuration, and also accepts the units d (24h) and w (7d).
+func parseLongDuration(s string) (time.Duration, error) {
+	expanded := longDurationUnit.ReplaceAllStringFunc(s, func(match string) string {
+		parts := longDurationUnit.FindStringSubmatch(match)
+		// The pattern only matches valid numbers.
+		n, _ := strconv.ParseFloat(parts[1], 64)
+		hours := 24.0
+		if parts[2] == "w" {
+			hours *= 7
+		}
+		return strconv.FormatFloat(n*hours, 'f', -1, 64) + "h"
+	})
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// longDurationValue is a pflag.Value for time.Duration that also accepts days and weeks.
+type longDurationValue struct {
+	value *time.Duration
+}
+
+func newLongDurationValue(p *time.Duration) *longDurationValue {
+	return &longDurationValue{value: p}
+}
+
+func (d *longDurationValue) Set(s string) error {
+	v, err := parseLongDuration(s)
+	if err != nil {
+		return err
+	}
+	*d.value = v
+	return nil
+}
+
+func (d *longDurationValue) String() string {
+	return d.value.String()
+}
+
+func (d *longDurationValue) Type() string {
+	return "duration"
+}
+
+// base64StringValue is a pflag.Value for a string field tagged encoding:"base64", which is set from base64.
+type base64StringValue struct {
+	value *string
+}
+
+func newBase64StringValue(p *string) *base64StringValue {
+	return &base64StringValue{value: p}
+}
+
+func (b *base64StringValue) Set(s string) error {
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return errors.New("invalid base64")
+	}
+	*b.value = string(decoded)
+	return nil
+}
+
+func (b *base64StringValue) String() string {
+	return base64.StdEncoding.EncodeToString([]byte(*b.value))
+}
+
+func (b *base64StringValue) Type() string {
+	return "string"
+}
+
+// isBase64 returns whether v is the value of a string field tagged encoding:"base64", or of a pointer to one.
+func isBase64(v pflag.Value) bool {
+	if p, ok := v.(*pointerValue); ok {
+		v = p.inner
+	}
+	_, ok := v.(*base64StringValue)
+	return ok
+}
+
+// plainString returns the value of a flag as its field holds it, which for base64 fields is the decoded string
+// rather than the flag form.
+func plainString(v pflag.Value) string {
+	s := v.String()
+	if !isBase64(v) {
+		return s
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return string(decoded)
+}
+
+// setPlainString sets a flag to a value as its field holds it, which for base64 fields is the decoded string.
+func setPlainString(v pflag.Value, s string) error {
+	if isBase64(v) {
+		s = base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	return v.Set(s)
+}
+
+// negatedBoolValue is a pflag.Value for the --no-<flag> negation of a bool field.
+// Setting it to true sets the field to false.
+type negatedBoolValue struct {
+	value *bool
+}
+
+func newNegatedBoolValue(p *bool) *negatedBoolValue {
+	return &negatedBoolValue{value: p}
+}
+
+func (b *negatedBoolValue) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.value = !v
+	return nil
+}
+
+func (b *negatedBoolValue) String() string {
+	return strconv.FormatBool(!*b.value)
+}
+
+func (b *negatedBoolValue) Type() string {
+	return "bool"
+}
+
+func (b *negatedBoolValue) IsBoolFlag() bool {
+	return true
+}
+
+// interfaceValue is a pflag.Value for an interface field, parsed as the type of the value it holds.
+type interfaceValue struct {
+	field reflect.Value
+	// concrete is the value of the inner flag, which is copied to the field on Set.
+	concrete reflect.Value
+	inner    pflag.Value
+}
+
+// newInterfaceValue returns a value for an interface field that holds a value of a supported scalar type,
+// along with the NoOptDefVal of its flag.
+func newInterfaceValue(nameTag string, field reflect.Value) (*interfaceValue, string, error) {
+	dynamic := field.Elem().Type()
+	switch dynamic.Kind() {
+	case reflect.Interface, reflect.Slice, reflect.Map, reflect.Struct:
+		return nil, "", fmt.Errorf("unsupported dynamic type %s", dynamic)
+	}
+	concrete, f, err := fieldFlag(nameTag, reflect.StructField{}, dynamic, field.Elem())
+	if err != nil {
+		return nil, "", err
+	}
+	return &interfaceValue{field: field, concrete: concrete, inner: f.Value}, f.NoOptDefVal, nil
+}
+
+func (i *interfaceValue) Set(s string) error {
+	if err := i.inner.Set(s); err != nil {
+		return err
+	}
+	i.field.Set(i.concrete)
+	return nil
+}
+
+func (i *interfaceValue) String() string {
+	// The field may have been set directly, for example from the config file.
+	if i.field.IsNil() || i.field.Elem().Type() != i.concrete.Type() {
+		return fmt.Sprint(i.field.Interface())
+	}
+	i.concrete.Set(i.field.Elem())
+	return i.inner.String()
+}
+
+func (i *interfaceValue) Type() string {
+	return i.inner.Type()
+}
+
+// pointerValue is a pflag.Value for a pointer to a scalar, parsed as the type it points to.
+// A nil pointer is only allocated when the flag is set.
+type pointerValue struct {
+	field reflect.Value
+	// concrete is the value of the inner flag, which is copied to a new pointer on Set.
+	concrete reflect.Value
+	inner    pflag.Value
+}
+
+// newPointerValue returns a value for a pointer field, along with the NoOptDefVal of its flag.
+func newPointerValue(nameTag string, structField reflect.StructField, field reflect.Value) (*pointerValue, string, error) {
+	elem := field.Type().Elem()
+	switch elem.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+		return nil, "", fmt.Errorf("unsupported pointer type %s", field.Type())
+	case reflect.Struct:
+		if elem != timeType {
+			return nil, "", fmt.Errorf("unsupported pointer type %s", field.Type())
+		}
+	}
+	var initial reflect.Value
+	if !field.IsNil() {
+		initial = field.Elem()
+	}
+	concrete, f, err := fieldFlag(nameTag, structField, elem, initial)
+	if err != nil {
+		return nil, "", err
+	}
+	return &pointerValue{field: field, concrete: concrete, inner: f.Value}, f.NoOptDefVal, nil
+}
+
+func (p *pointerValue) Set(s string) error {
+	if !p.field.IsNil() {
+		p.concrete.Set(p.field.Elem())
+	}
+	if err := p.inner.Set(s); err != nil {
+		return err
+	}
+	// Allocate a new pointer rather than writing through the old one, which may be shared, e.g. with the default.
+	ptr := reflect.New(p.concrete.Type())
+	ptr.Elem().Set(p.concrete)
+	p.field.Set(ptr)
+	return nil
+}
+
+func (p *pointerValue) String() string {
+	// The field may have been set directly, for example from the config file.
+	if p.field.IsNil() {
+		return ""
+	}
+	p.concrete.Set(p.field.Elem())
+	return p.inner.String()
+}
+
+func (p *pointerValue) Type() string {
+	return p.inner.Type()
+}
+
+// isUnset returns whether v is the value of a nil pointer field, which only a source may allocate.
+func isUnset(v pflag.Value) bool {
+	p, ok := v.(*pointerValue)
+	return ok && p.field.IsNil()
+}
+
+// unitValue wraps the pflag.Value of a numeric flag to parse values with units, such as 10/s, with a unit parser.
+// String returns the last value with units that was set while the flag still holds it, so it can be set back.
+type unitValue struct {
+	pflag.Value
+	parse func(string) (float64, error)
+	// raw is the last value set, and parsed its number as set on the wrapped value.
+	raw    string
+	parsed string
+}
+
+func newUnitValue(inner pflag.Value, parse func(string) (float64, error)) *unitValue {
+	return &unitValue{Value: inner, parse: parse}
+}
+
+func (u *unitValue) Set(s string) error {
+	v, err := u.parse(s)
+	if err != nil {
+		return err
+	}
+	if err := u.Value.Set(strconv.FormatFloat(v, 'f', -1, 64)); err != nil {
+		return err
+	}
+	u.raw, u.parsed = s, u.Value.String()
+	return nil
+}
+
+func (u *unitValue) String() string {
+	if u.raw != "" && u.Value.String() == u.parsed {
+		return u.raw
+	}
+	return u.Value.String()
+}
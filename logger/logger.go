@@ -8,30 +8,193 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
 )
 
 type loggerKeyType string
 
-var loggerKey loggerKeyType = "logger"
+var (
+	loggerKey     loggerKeyType = "logger"
+	extractorsKey loggerKeyType = "extractors"
+	levelKey      loggerKeyType = "level"
+)
+
+// maskedValue replaces the values of redacted attributes.
+const maskedValue = "******"
 
 // NewContext returns a new context with a logger.
 // Call this function at the start of the program and use this as the base context.
-func NewContext(w io.Writer, level slog.Level) context.Context {
+// The level can be changed later with InstallSignalLevelToggle.
+// The values of attributes whose key contains one of redactKeys, ignoring case, are masked, e.g. "password" or "token".
+func NewContext(w io.Writer, level slog.Level, redactKeys ...string) context.Context {
 	ctx := context.Background()
-	logger := slog.New(
-		slog.NewJSONHandler(w, &slog.HandlerOptions{
-			Level: level,
-		}),
-	)
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(level)
+	opts := &slog.HandlerOptions{
+		Level: levelVar,
+	}
+	if len(redactKeys) > 0 {
+		opts.ReplaceAttr = redactAttr(redactKeys)
+	}
+	logger := slog.New(slog.NewJSONHandler(w, opts))
+	ctx = context.WithValue(ctx, levelKey, levelVar)
 	return context.WithValue(ctx, loggerKey, logger)
 }
 
+// redactAttr returns a slog.HandlerOptions.ReplaceAttr function that masks the attributes whose key contains one of
+// keys, ignoring case.
+func redactAttr(keys []string) func(groups []string, a slog.Attr) slog.Attr {
+	lower := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key != "" {
+			lower = append(lower, strings.ToLower(key))
+		}
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// The built-in attributes, such as msg, are never masked.
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey || a.Key == slog.SourceKey) {
+			return a
+		}
+		key := strings.ToLower(a.Key)
+		for _, redact := range lower {
+			if strings.Contains(key, redact) {
+				return slog.String(a.Key, maskedValue)
+			}
+		}
+		return a
+	}
+}
+
 // FromContext retrieves a logger from a context and panics if there isn't one.
+// The logger includes the attributes returned by the extractors added with WithExtractor.
 func FromContext(ctx context.Context) *slog.Logger {
 	val := ctx.Value(loggerKey)
 	logger, ok := val.(*slog.Logger)
 	if !ok {
 		panic("No logger in context")
 	}
+	extractors, _ := ctx.Value(extractorsKey).([]func(context.Context) []any)
+	for _, extract := range extractors {
+		if args := extract(ctx); len(args) > 0 {
+			logger = logger.With(args...)
+		}
+	}
 	return logger
 }
+
+// WithExtractor returns a new context with an extractor that FromContext calls to add attributes to the logger.
+// The extractor receives the context passed to FromContext and returns key-value pairs like slog.Logger.With,
+// so values set in the context later, for example by middleware, are included.
+func WithExtractor(ctx context.Context, extract func(context.Context) []any) context.Context {
+	extractors, _ := ctx.Value(extractorsKey).([]func(context.Context) []any)
+	extractors = append(extractors[:len(extractors):len(extractors)], extract)
+	return context.WithValue(ctx, extractorsKey, extractors)
+}
+
+// Level returns the current level of the logger created by NewContext.
+// It panics if ctx has no logger from NewContext. Use FromContext(ctx).Enabled to check a level instead.
+func Level(ctx context.Context) slog.Level {
+	return contextLevel(ctx).Level()
+}
+
+// contextLevel returns the level of the logger in ctx, and panics if there isn't one.
+func contextLevel(ctx context.Context) *slog.LevelVar {
+	levelVar, ok := ctx.Value(levelKey).(*slog.LevelVar)
+	if !ok {
+		panic("No logger level in context")
+	}
+	return levelVar
+}
+
+// InstallSignalLevelToggle cycles the level of the logger created by NewContext through levels each time
+// the process receives sig, e.g. syscall.SIGUSR1, until ctx is done.
+// A level that isn't in levels moves to the first one. It panics if ctx has no logger from NewContext.
+func InstallSignalLevelToggle(ctx context.Context, sig os.Signal, levels ...slog.Level) {
+	toggle := levelToggle(ctx, levels)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				toggle()
+			}
+		}
+	}()
+}
+
+// levelToggle returns a function that moves the level of the logger in ctx to the next one of levels.
+func levelToggle(ctx context.Context, levels []slog.Level) func() {
+	levelVar := contextLevel(ctx)
+	return func() {
+		if len(levels) == 0 {
+			return
+		}
+		next := slices.Index(levels, levelVar.Level()) + 1
+		levelVar.Set(levels[next%len(levels)])
+	}
+}
+
+// Recover logs a panic at error level with its stack trace, using the logger in ctx.
+// It must be deferred directly, e.g. defer logger.Recover(ctx, false).
+// If rethrow is true, the panic continues once it's logged.
+func Recover(ctx context.Context, rethrow bool) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	FromContext(ctx).ErrorContext(ctx, "recovered from panic", "panic", r, "stack", string(debug.Stack()))
+	if rethrow {
+		panic(r)
+	}
+}
+
+// LogAt logs a message at level with t as its time instead of the current time, using the logger in ctx.
+// Use it to log events that happened earlier, for example when replaying them.
+// Args are key-value pairs like slog.Logger.Log.
+func LogAt(ctx context.Context, t time.Time, level slog.Level, msg string, args ...any) {
+	handler := FromContext(ctx).Handler()
+	if !handler.Enabled(ctx, level) {
+		return
+	}
+	record := slog.NewRecord(t, level, msg, 0)
+	record.Add(args...)
+	// Like slog.Logger.Log, ignore the handler's errors.
+	_ = handler.Handle(ctx, record)
+}
+
+// Writer returns an io.Writer that logs each line written to it at level, using the logger in ctx.
+// Use it to pass the logger to code that expects a *log.Logger or an io.Writer, for example with log.New.
+func Writer(ctx context.Context, level slog.Level) io.Writer {
+	return &lineWriter{
+		ctx:    ctx,
+		logger: FromContext(ctx),
+		level:  level,
+	}
+}
+
+// lineWriter logs each non-empty line written to it as a separate message.
+type lineWriter struct {
+	ctx    context.Context
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		w.logger.Log(w.ctx, w.level, line)
+	}
+	return len(p), nil
+}
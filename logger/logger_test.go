@@ -6,8 +6,12 @@ package logger
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"log"
 	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -67,3 +71,150 @@ func TestFromContextPanics(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo)
+
+	l := log.New(Writer(ctx, slog.LevelWarn), "", 0)
+	l.Print("first line\nsecond line")
+	l.Print("third line")
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "WARN", record["level"])
+		messages = append(messages, record["msg"].(string))
+	}
+	assert.Equal(t, []string{"first line", "second line", "third line"}, messages)
+
+	buf.Reset()
+	_, err := Writer(ctx, slog.LevelDebug).Write([]byte("dropped\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestLogAt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo)
+
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	LogAt(ctx, at, slog.LevelWarn, "replayed", "event", 42)
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, at.Format(time.RFC3339Nano), record["time"])
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "replayed", record["msg"])
+	assert.Equal(t, float64(42), record["event"])
+
+	buf.Reset()
+	LogAt(ctx, at, slog.LevelDebug, "dropped")
+	assert.Empty(t, buf.String())
+}
+
+func TestNewContextRedactKeys(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo, "password", "Token")
+
+	FromContext(ctx).Info("login", "user", "alice", "db_password", "hunter2", slog.Group("auth", "accessToken", "abc"))
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "login", record["msg"])
+	assert.Equal(t, "alice", record["user"])
+	assert.Equal(t, "******", record["db_password"])
+	assert.Equal(t, map[string]any{"accessToken": "******"}, record["auth"])
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
+func TestLevel(t *testing.T) {
+	ctx := NewContext(&bytes.Buffer{}, slog.LevelWarn)
+	assert.Equal(t, slog.LevelWarn, Level(ctx))
+
+	levelToggle(ctx, []slog.Level{slog.LevelDebug})()
+	assert.Equal(t, slog.LevelDebug, Level(ctx))
+	assert.True(t, FromContext(ctx).Enabled(ctx, slog.LevelDebug))
+
+	assert.PanicsWithValue(t, "No logger level in context", func() {
+		Level(context.Background())
+	})
+}
+
+func TestInstallSignalLevelToggle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo)
+
+	toggle := levelToggle(ctx, []slog.Level{slog.LevelInfo, slog.LevelDebug, slog.LevelError})
+	for _, expected := range []slog.Level{slog.LevelDebug, slog.LevelError, slog.LevelInfo, slog.LevelDebug} {
+		toggle()
+		assert.True(t, FromContext(ctx).Enabled(ctx, expected))
+		assert.False(t, FromContext(ctx).Enabled(ctx, expected-1))
+	}
+
+	FromContext(ctx).Debug("visible")
+	assert.Contains(t, buf.String(), "visible")
+
+	assert.PanicsWithValue(t, "No logger level in context", func() {
+		levelToggle(context.Background(), nil)
+	})
+}
+
+func TestRecover(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo)
+
+	assert.NotPanics(t, func() {
+		defer Recover(ctx, false)
+		panic("boom")
+	})
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "recovered from panic", record["msg"])
+	assert.Equal(t, "boom", record["panic"])
+	assert.Contains(t, record["stack"], "TestRecover")
+
+	buf.Reset()
+	assert.PanicsWithValue(t, "again", func() {
+		defer Recover(ctx, true)
+		panic("again")
+	})
+	assert.Contains(t, buf.String(), `"panic":"again"`)
+
+	buf.Reset()
+	assert.NotPanics(t, func() {
+		defer Recover(ctx, true)
+	})
+	assert.Empty(t, buf.String())
+}
+
+func TestWithExtractor(t *testing.T) {
+	type requestIDKeyType string
+	const requestIDKey requestIDKeyType = "request_id"
+
+	buf := &bytes.Buffer{}
+	ctx := NewContext(buf, slog.LevelInfo)
+	ctx = WithExtractor(ctx, func(ctx context.Context) []any {
+		id, ok := ctx.Value(requestIDKey).(string)
+		if !ok {
+			return nil
+		}
+		return []any{"request_id", id}
+	})
+
+	FromContext(ctx).Info("without request")
+	FromContext(context.WithValue(ctx, requestIDKey, "abc")).Info("with request")
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	assert.Len(t, records, 2)
+	assert.NotContains(t, records[0], "request_id")
+	assert.Equal(t, "abc", records[1]["request_id"])
+}